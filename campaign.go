@@ -0,0 +1,282 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"emailer/internal/argparse"
+	"emailer/internal/campaign"
+	"emailer/internal/config"
+	"emailer/internal/gmail"
+	"emailer/internal/imap"
+	"emailer/internal/policy"
+	"emailer/internal/scheduler"
+
+	"github.com/spf13/pflag"
+)
+
+// runCampaign drives the bulk/outreach send flow: read the recipients CSV,
+// render the subject/body template per row (honoring any per-row
+// overrides), draft (and optionally schedule) each email, and persist
+// progress so a re-run skips rows already sent.
+func runCampaign(campaignArgs []string, gmailClient *gmail.Client) error {
+	fs := pflag.NewFlagSet("campaign", pflag.ExitOnError)
+	rate := fs.String("rate", "", "Maximum send rate, e.g. 30/hour")
+	stateFilePath := fs.String("state", "campaign_state.json", "Path to the resumable campaign state file")
+	dryRun := fs.Bool("dry-run", false, "Render and print emails without drafting or scheduling them")
+	subjectArg := fs.StringP("subject", "s", "", fmt.Sprintf("The default subject template, overridden per-row by a 'subject' CSV column. Overrides the %s environment variable", config.EnvEmailSubject))
+	bodyPathArg := fs.StringP("message_body_path", "m", "", fmt.Sprintf("The path to the message body template. Overrides the %s environment variable", config.EnvMessageBodyPath))
+	attachmentPathArg := fs.StringP("attachment_path", "a", "", "Comma-separated default attachment paths, overridden per-row by an 'attachment_path' CSV column. Prefix a path with \"inline:\" to embed it as an inline image referenced from the HTML body via cid:<name>")
+	schedule := fs.Bool("schedule", false, "Schedule each email via the --transport backend instead of leaving it as a draft")
+	scheduleCsvPath := fs.StringP("schedule_csv_path", "v", "", "Default CSV of allowed send windows, overridden per-row by a 'schedule_csv_path' CSV column. Used when --schedule is set")
+	timezone := fs.StringP("timezone", "z", "UTC", "Timezone used to compute each recipient's send window")
+	emailAddress := fs.StringP("email_address", "e", "", fmt.Sprintf("The email address to send to the Streak API. Overrides the %s", config.EnvStreakEmailAddress))
+	tokenPath := fs.StringP("token_path", "t", "", fmt.Sprintf("The path to the token.json file. The default value is token.json. Overrides the %s environment variable", config.EnvTokenPath))
+	credsPath := fs.StringP("creds_path", "c", "", fmt.Sprintf("The path to the credentials.json file. Overrides the %s environment variable", config.EnvCredsPath))
+	allowlistPath := fs.String("allowlist", "", fmt.Sprintf("Path to a JSON allowlist of recipient addresses/domain globs. Overrides the %s environment variable", config.EnvAllowlistPath))
+	blocklistPath := fs.String("blocklist", "", fmt.Sprintf("Path to a JSON blocklist of recipient addresses/domain globs. Overrides the %s environment variable", config.EnvBlocklistPath))
+	transportArg := fs.StringP("transport", "", "", fmt.Sprintf("Which scheduler backend --schedule uses: streak or smtp. Overrides the %s environment variable", config.EnvTransport))
+	followupStorePath := fs.StringP("followup_store", "", "", "Path to the JSON store tracking sent messages and their scheduled follow-up drafts (see 'emailer watch'). Required to auto-cancel rows with an 'in_reply_to_message_id' CSV column if the recipient replies to that earlier message first")
+
+	if err := fs.Parse(campaignArgs); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: %s campaign <recipients.csv> [flags]", os.Args[0])
+	}
+	csvPath := fs.Arg(0)
+
+	minDelay, err := campaign.ParseRate(*rate)
+	if err != nil {
+		return err
+	}
+
+	sendPolicy, err := policy.Load(
+		argparse.GetArgOrEnv(*allowlistPath, config.EnvAllowlistPath, false, ""),
+		argparse.GetArgOrEnv(*blocklistPath, config.EnvBlocklistPath, false, ""),
+		*dryRun,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to load allowlist/blocklist: %v", err)
+	}
+
+	saKeyPath := argparse.GetArgOrEnv("", config.EnvSAKeyPath, false, "")
+	impersonateUser := argparse.GetArgOrEnv("", config.EnvImpersonateUser, false, "")
+
+	resolvedTokenPath := argparse.GetArgOrEnv(*tokenPath, config.EnvTokenPath, false, "token.json")
+	resolvedCredsPath := argparse.GetArgOrEnv(*credsPath, config.EnvCredsPath, false, "")
+
+	creds, err := authenticateGmail(gmailClient, resolvedTokenPath, resolvedCredsPath, saKeyPath, impersonateUser)
+	if err != nil {
+		return fmt.Errorf("authentication failed: %v", err)
+	}
+	if saKeyPath == "" {
+		if err := saveCredentials(creds, resolvedTokenPath); err != nil {
+			log.Printf("Warning: Failed to save credentials: %v", err)
+		}
+	}
+
+	defaultSubject := argparse.GetArgOrEnv(*subjectArg, config.EnvEmailSubject, true, "")
+	messageBodyPath := argparse.GetArgOrEnv(*bodyPathArg, config.EnvMessageBodyPath, true, "")
+
+	templateContent, err := os.ReadFile(messageBodyPath)
+	if err != nil {
+		return fmt.Errorf("failed to read message template: %v", err)
+	}
+
+	rows, err := campaign.ParseCSV(csvPath)
+	if err != nil {
+		return err
+	}
+
+	state := campaign.LoadState(*stateFilePath)
+
+	if *schedule && *scheduleCsvPath != "" {
+		if _, err := scheduler.ParseTimeRangesCSV(*scheduleCsvPath); err != nil {
+			return fmt.Errorf("failed to parse schedule CSV: %v", err)
+		}
+	}
+
+	*emailAddress = argparse.GetArgOrEnv(*emailAddress, config.EnvStreakEmailAddress, false, "")
+	if *emailAddress == "" && *schedule {
+		if user, err := gmailClient.GetCurrentUser(); err != nil {
+			log.Printf("Warning: Failed to get current user: %v", err)
+		} else {
+			*emailAddress = user.EmailAddress
+		}
+	}
+
+	var sched scheduler.Scheduler
+	if *schedule {
+		sched, err = buildScheduler(argparse.GetArgOrEnv(*transportArg, config.EnvTransport, false, "streak"))
+		if err != nil {
+			return fmt.Errorf("failed to configure scheduler: %v", err)
+		}
+	}
+
+	var followupStore *imap.Store
+	if *followupStorePath != "" {
+		followupStore, err = imap.OpenStore(*followupStorePath)
+		if err != nil {
+			return fmt.Errorf("failed to open follow-up store: %v", err)
+		}
+	}
+
+	var sent, failed, skipped, blocked int
+
+	for i, row := range rows {
+		key := campaign.Key(row)
+
+		if existing, ok := state[key]; ok && existing.Status == "sent" {
+			skipped++
+			continue
+		}
+
+		if err := sendPolicy.Check(row.Email); err != nil {
+			state[key] = &campaign.Status{Status: "blocked", Error: err.Error()}
+			blocked++
+			continue
+		}
+
+		data := map[string]string{
+			"recruiter_company": row.Company,
+			"recruiter_name":    row.Name,
+			"company":           row.Company,
+			"name":              row.Name,
+			"email":             row.Email,
+		}
+		for k, v := range row.Extra {
+			data[k] = v
+		}
+
+		rowSubject := defaultSubject
+		if row.Subject != "" {
+			rowSubject = row.Subject
+		}
+
+		renderedSubject, err := processTemplate(rowSubject, data)
+		if err != nil {
+			state[key] = &campaign.Status{Status: "failed", Error: err.Error()}
+			failed++
+			continue
+		}
+		renderedBody, err := processTemplate(string(templateContent), data)
+		if err != nil {
+			state[key] = &campaign.Status{Status: "failed", Error: err.Error()}
+			failed++
+			continue
+		}
+
+		if *dryRun {
+			fmt.Printf("--- %s <%s> ---\nSubject: %s\n%s\n\n", row.Name, row.Email, renderedSubject, renderedBody)
+			continue
+		}
+
+		rowAttachmentPath := *attachmentPathArg
+		if row.AttachmentPath != "" {
+			rowAttachmentPath = row.AttachmentPath
+		}
+		attachments, err := loadAttachments(rowAttachmentPath)
+		if err != nil {
+			state[key] = &campaign.Status{Status: "failed", Error: err.Error()}
+			failed++
+			continue
+		}
+
+		message, err := gmail.CreateEmailMessage(renderedBody, row.Email, renderedSubject, attachments)
+		if err != nil {
+			state[key] = &campaign.Status{Status: "failed", Error: err.Error()}
+			failed++
+			continue
+		}
+		rawMessage, err := base64.URLEncoding.DecodeString(message.Raw)
+		if err != nil {
+			log.Printf("Warning: failed to decode message for scheduling: %v", err)
+		}
+
+		throttle := func() {
+			if minDelay > 0 && i < len(rows)-1 {
+				time.Sleep(minDelay)
+			}
+		}
+
+		draft, err := gmailClient.SaveDraft(message)
+		if err != nil {
+			state[key] = &campaign.Status{Status: "failed", Error: err.Error()}
+			failed++
+			state.Save(*stateFilePath)
+			throttle()
+			continue
+		}
+
+		status := &campaign.Status{Status: "queued", DraftID: draft.Id}
+
+		if *schedule {
+			rowScheduleCsvPath := *scheduleCsvPath
+			if row.ScheduleCsvPath != "" {
+				rowScheduleCsvPath = row.ScheduleCsvPath
+			}
+
+			sendTime, err := scheduleSend(*timezone, rowScheduleCsvPath, draft, rawMessage, sched, *emailAddress, row.Email, renderedSubject)
+			if err != nil {
+				status.Status = "failed"
+				status.Error = err.Error()
+				failed++
+			} else {
+				status.Status = "sent"
+				status.ScheduledFor = sendTime.Format(time.RFC3339)
+				sent++
+
+				if row.InReplyToMessageID != "" && followupStore != nil {
+					followUp := imap.FollowUp{Recipient: row.Email, DraftID: draft.Id, ThreadID: draft.Message.ThreadId}
+					if err := followupStore.Put(row.InReplyToMessageID, followUp); err != nil {
+						log.Printf("Warning: failed to record follow-up for %s: %v", row.Email, err)
+					}
+				}
+			}
+		} else {
+			status.Status = "sent"
+			sent++
+		}
+
+		state[key] = status
+		if err := state.Save(*stateFilePath); err != nil {
+			log.Printf("Warning: failed to persist campaign state: %v", err)
+		}
+
+		throttle()
+	}
+
+	fmt.Printf("Campaign summary: %d sent, %d failed, %d blocked by policy, %d skipped (already sent)\n", sent, failed, blocked, skipped)
+	return nil
+}
+
+// loadAttachments reads the comma-separated attachment paths in spec (each
+// optionally "inline:"-prefixed, see gmail.SplitAttachmentPath) into
+// gmail.Attachments named after their base filename.
+func loadAttachments(spec string) ([]gmail.Attachment, error) {
+	if spec == "" {
+		return nil, nil
+	}
+
+	var attachments []gmail.Attachment
+	for _, path := range strings.Split(spec, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+
+		cleanPath, inline := gmail.SplitAttachmentPath(path)
+		data, err := os.ReadFile(cleanPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read attachment %q: %v", cleanPath, err)
+		}
+		attachments = append(attachments, gmail.NewAttachment(filepath.Base(cleanPath), data, inline))
+	}
+	return attachments, nil
+}
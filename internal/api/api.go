@@ -0,0 +1,445 @@
+// Package api exposes the emailer as a long-lived HTTP service so other
+// tools can submit email jobs without shelling out to the CLI.
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"emailer/internal/argparse"
+	"emailer/internal/config"
+	"emailer/internal/gmail"
+	"emailer/internal/policy"
+	"emailer/internal/scheduler"
+	"emailer/internal/streak"
+	"emailer/internal/tokens"
+)
+
+// unsubscribeTokenMaxAge bounds how long an unsubscribe link stays valid,
+// matching how long the campaign tools that generate them typically run.
+const unsubscribeTokenMaxAge = 90 * 24 * time.Hour
+
+// Config holds the settings needed to run the HTTP API server.
+type Config struct {
+	Addr               string
+	AuthToken          string
+	StreakToken        string
+	StreakEmailAddress string
+	ScheduleCsvPath    string
+	Timezone           string
+	Policy             *policy.Policy
+	TokenSecret        string
+	BlocklistPath      string
+}
+
+// messageRequest is the JSON body accepted by POST /message.
+type messageRequest struct {
+	To          string              `json:"to"`
+	Subject     string              `json:"subject"`
+	Body        string              `json:"body"`
+	Attachments []attachmentRequest `json:"attachments,omitempty"`
+	Schedule    bool                `json:"schedule,omitempty"`
+}
+
+// attachmentRequest is a single base64-encoded attachment. Setting Inline
+// embeds it as an inline image (Content-Disposition: inline plus a
+// Content-ID set to Name) instead of a regular attachment, so the HTML body
+// can reference it via cid:<name>.
+type attachmentRequest struct {
+	Name   string `json:"name"`
+	Data   string `json:"data"`
+	Inline bool   `json:"inline,omitempty"`
+}
+
+type messageResponse struct {
+	DraftID      string `json:"draft_id"`
+	ScheduledFor string `json:"scheduled_for,omitempty"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// draftRequest is the JSON body accepted by POST /drafts.
+type draftRequest struct {
+	To          string              `json:"to"`
+	Subject     string              `json:"subject"`
+	Body        string              `json:"body"`
+	Attachments []attachmentRequest `json:"attachments,omitempty"`
+}
+
+type draftResponse struct {
+	DraftID  string `json:"draft_id"`
+	ThreadID string `json:"thread_id,omitempty"`
+}
+
+// scheduleRequest is the JSON body accepted by POST /drafts/{id}/schedule.
+type scheduleRequest struct {
+	To              string `json:"to"`
+	Subject         string `json:"subject"`
+	Timezone        string `json:"timezone"`
+	ScheduleCsvPath string `json:"schedule_csv_path"`
+}
+
+type scheduleResponse struct {
+	ScheduledFor string `json:"scheduled_for"`
+}
+
+// Serve starts the HTTP API server and blocks until it exits or an error occurs.
+func Serve(cfg *Config, gmailClient *gmail.Client) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/message", cfg.requireAuth(cfg.handleMessage(gmailClient)))
+	mux.HandleFunc("/unsubscribe", cfg.handleUnsubscribe())
+	mux.HandleFunc("/drafts", cfg.requireAuth(cfg.handleCreateDraft(gmailClient)))
+	mux.HandleFunc("/drafts/", cfg.requireAuth(cfg.handleScheduleDraft(gmailClient)))
+	mux.HandleFunc("/me", cfg.requireAuth(cfg.handleMe(gmailClient)))
+
+	log.Printf("API server listening on %s", cfg.Addr)
+	return http.ListenAndServe(cfg.Addr, mux)
+}
+
+// requireAuth gates a handler behind the EMAILER_API_TOKEN bearer token.
+func (cfg *Config) requireAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if cfg.AuthToken == "" {
+			writeError(w, http.StatusInternalServerError, fmt.Errorf("server missing %s", config.EnvAPIToken))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer "+cfg.AuthToken {
+			writeError(w, http.StatusUnauthorized, fmt.Errorf("invalid or missing bearer token"))
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (cfg *Config) handleMessage(gmailClient *gmail.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+			return
+		}
+
+		var req messageRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid JSON body: %v", err))
+			return
+		}
+
+		if err := validateMessageRequest(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if cfg.Policy != nil {
+			if err := cfg.Policy.Check(req.To); err != nil {
+				writeError(w, http.StatusForbidden, err)
+				return
+			}
+		}
+
+		attachments, err := decodeAttachments(req.Attachments)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if cfg.Policy != nil && cfg.Policy.DryRun {
+			writeJSON(w, http.StatusOK, messageResponse{DraftID: "(dry-run)"})
+			return
+		}
+
+		message, err := gmail.CreateEmailMessage(req.Body, req.To, req.Subject, attachments)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("failed to build message: %v", err))
+			return
+		}
+
+		draft, err := gmailClient.SaveDraft(message)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to save draft: %v", err))
+			return
+		}
+
+		resp := messageResponse{DraftID: draft.Id}
+
+		if req.Schedule {
+			sendTime, err := cfg.scheduleDraft(draft, req.To, req.Subject)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to schedule message: %v", err))
+				return
+			}
+			resp.ScheduledFor = sendTime.Format(time.RFC3339)
+		}
+
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+// handleCreateDraft runs the same authentication + MIME-assembly +
+// SaveDraft flow as the CLI, scoped to one JSON request.
+func (cfg *Config) handleCreateDraft(gmailClient *gmail.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+			return
+		}
+
+		var req draftRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid JSON body: %v", err))
+			return
+		}
+
+		if err := validateMessageRequest(&messageRequest{To: req.To, Subject: req.Subject, Body: req.Body}); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if cfg.Policy != nil {
+			if err := cfg.Policy.Check(req.To); err != nil {
+				writeError(w, http.StatusForbidden, err)
+				return
+			}
+		}
+
+		attachments, err := decodeAttachments(req.Attachments)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if cfg.Policy != nil && cfg.Policy.DryRun {
+			writeJSON(w, http.StatusOK, draftResponse{DraftID: "(dry-run)"})
+			return
+		}
+
+		message, err := gmail.CreateEmailMessage(req.Body, req.To, req.Subject, attachments)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("failed to build message: %v", err))
+			return
+		}
+
+		draft, err := gmailClient.SaveDraft(message)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to save draft: %v", err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, draftResponse{DraftID: draft.Id, ThreadID: draft.Message.ThreadId})
+	}
+}
+
+// handleScheduleDraft handles POST /drafts/{id}/schedule, fetching the
+// draft to recover its thread ID and handing it off to Streak.
+func (cfg *Config) handleScheduleDraft(gmailClient *gmail.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+			return
+		}
+
+		draftID, ok := parseDraftScheduleID(r.URL.Path)
+		if !ok {
+			writeError(w, http.StatusNotFound, fmt.Errorf("not found"))
+			return
+		}
+
+		var req scheduleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid JSON body: %v", err))
+			return
+		}
+
+		draft, err := gmailClient.GetDraft(draftID)
+		if err != nil {
+			writeError(w, http.StatusNotFound, fmt.Errorf("draft not found: %v", err))
+			return
+		}
+
+		timezone := req.Timezone
+		if timezone == "" {
+			timezone = cfg.Timezone
+		}
+		csvPath := req.ScheduleCsvPath
+		if csvPath == "" {
+			csvPath = cfg.ScheduleCsvPath
+		}
+
+		sendTime, err := cfg.scheduleDraftWith(draft, req.To, req.Subject, timezone, csvPath)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to schedule draft: %v", err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, scheduleResponse{ScheduledFor: sendTime.Format(time.RFC3339)})
+	}
+}
+
+// parseDraftScheduleID extracts the draft ID from a "/drafts/{id}/schedule" path.
+func parseDraftScheduleID(path string) (id string, ok bool) {
+	rest := strings.TrimPrefix(path, "/drafts/")
+	if rest == path {
+		return "", false
+	}
+
+	parts := strings.Split(strings.Trim(rest, "/"), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] != "schedule" {
+		return "", false
+	}
+	return parts[0], true
+}
+
+// handleMe returns the authenticated Gmail user's profile.
+func (cfg *Config) handleMe(gmailClient *gmail.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+			return
+		}
+
+		profile, err := gmailClient.GetCurrentUser()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to get current user: %v", err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, profile)
+	}
+}
+
+// scheduleDraft mirrors the CLI's scheduleSend, computing a send time from
+// the configured schedule CSV and handing the draft off to Streak.
+func (cfg *Config) scheduleDraft(draft *gmail.Draft, to, subject string) (*time.Time, error) {
+	return cfg.scheduleDraftWith(draft, to, subject, cfg.Timezone, cfg.ScheduleCsvPath)
+}
+
+// scheduleDraftWith is scheduleDraft with an explicit timezone/CSV path, so
+// POST /drafts/{id}/schedule can override the server-wide defaults per call.
+func (cfg *Config) scheduleDraftWith(draft *gmail.Draft, to, subject, timezone, csvPath string) (*time.Time, error) {
+	if cfg.StreakToken == "" {
+		return nil, fmt.Errorf("no streak token configured")
+	}
+	if csvPath == "" {
+		return nil, fmt.Errorf("no schedule csv configured")
+	}
+
+	dayRanges, err := scheduler.ParseTimeRangesCSV(csvPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse schedule CSV: %v", err)
+	}
+
+	sendTime, err := scheduler.GetScheduledSendTime(dayRanges, timezone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute send time: %v", err)
+	}
+
+	if sendTime == nil {
+		loc, err := time.LoadLocation(timezone)
+		if err != nil {
+			loc = time.UTC
+		}
+		now := time.Now().In(loc).Add(10 * time.Minute)
+		sendTime = &now
+	}
+
+	if err := streak.ScheduleSendLater(&streak.SendLaterConfig{
+		Token:        cfg.StreakToken,
+		ToAddress:    to,
+		Subject:      subject,
+		ThreadID:     draft.Message.ThreadId,
+		DraftID:      draft.Id,
+		SendDate:     *sendTime,
+		IsTracked:    true,
+		EmailAddress: cfg.StreakEmailAddress,
+	}); err != nil {
+		return nil, err
+	}
+
+	return sendTime, nil
+}
+
+// handleUnsubscribe validates a one-click unsubscribe token and adds its
+// recipient to the blocklist file. It's deliberately not behind requireAuth
+// since it's meant to be hit directly from a link in an email.
+func (cfg *Config) handleUnsubscribe() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if cfg.TokenSecret == "" {
+			writeError(w, http.StatusInternalServerError, fmt.Errorf("server missing %s", config.EnvTokenSecret))
+			return
+		}
+
+		token := r.URL.Query().Get("t")
+		if token == "" {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("missing token"))
+			return
+		}
+
+		email, ok := tokens.Verify([]byte(cfg.TokenSecret), token, tokens.PurposeUnsubscribe, unsubscribeTokenMaxAge)
+		if !ok {
+			writeError(w, http.StatusForbidden, fmt.Errorf("invalid or expired unsubscribe token"))
+			return
+		}
+
+		if err := policy.AppendToBlocklist(cfg.BlocklistPath, email); err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to update blocklist: %v", err))
+			return
+		}
+
+		log.Printf("Unsubscribed %s", email)
+		w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+		fmt.Fprintf(w, "<p>%s has been unsubscribed.</p>", email)
+	}
+}
+
+func validateMessageRequest(req *messageRequest) error {
+	if strings.TrimSpace(req.To) == "" {
+		return fmt.Errorf("to is required")
+	}
+	if !argparse.IsValidEmail(req.To) {
+		return fmt.Errorf("invalid email format: %s", req.To)
+	}
+	if strings.TrimSpace(req.Subject) == "" {
+		return fmt.Errorf("subject is required")
+	}
+	if strings.TrimSpace(req.Body) == "" {
+		return fmt.Errorf("body is required")
+	}
+	return nil
+}
+
+// decodeAttachments base64-decodes every attachmentRequest into a
+// gmail.Attachment, preserving each one's Inline flag.
+func decodeAttachments(reqAttachments []attachmentRequest) ([]gmail.Attachment, error) {
+	if len(reqAttachments) == 0 {
+		return nil, nil
+	}
+
+	attachments := make([]gmail.Attachment, 0, len(reqAttachments))
+	for _, a := range reqAttachments {
+		data, err := base64.StdEncoding.DecodeString(a.Data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid attachment data for %q: %v", a.Name, err)
+		}
+		attachments = append(attachments, gmail.NewAttachment(a.Name, data, a.Inline))
+	}
+	return attachments, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, payload interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(payload); err != nil {
+		log.Printf("failed to write JSON response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	log.Printf("API error (%d): %v", status, err)
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
@@ -11,20 +11,31 @@ import (
 )
 
 type Args struct {
-	recruiterCompany string
-	recruiterName    string
-	recruiterEmail   string
-	attachmentPath   string
-	attachmentName   string
-	subject          string
-	messageBodyPath  string
-	timezone         string
-	schedule         bool
-	scheduleCsvPath  string
-	emailAddress     string
-	tokenPath        string
-	credsPath        string
-	help             bool
+	recruiterCompany  string
+	recruiterName     string
+	recruiterEmail    string
+	attachmentPaths   []string
+	attachmentNames   []string
+	subject           string
+	messageBodyPath   string
+	timezone          string
+	schedule          bool
+	scheduleCsvPath   string
+	emailAddress      string
+	tokenPath         string
+	credsPath         string
+	help              bool
+	serve             bool
+	apiPort           string
+	allowlistPath     string
+	blocklistPath     string
+	dryRun            bool
+	saKeyPath         string
+	impersonateUser   string
+	actionLink        string
+	actionName        string
+	actionDescription string
+	transport         string
 }
 
 func printUsage(isError bool) {
@@ -86,11 +97,44 @@ func ParseArgs(args *Args) {
 	pflag.StringVarP(&args.credsPath, "creds_path", "c", "",
 		fmt.Sprintf("The path to the credentials.json file. The default value is credentials.json. Overrides the %s environment variable", config.EnvCredsPath))
 
-	pflag.StringVarP(&args.attachmentPath, "attachment_path", "a", "",
-		fmt.Sprintf("The path to the attachment file, if this is provided, attachment_name must also be provided. Overrides the %s environment variable", config.EnvAttachmentPath))
+	pflag.StringArrayVarP(&args.attachmentPaths, "attachment_path", "a", nil,
+		fmt.Sprintf("The path to an attachment file. May be repeated to send multiple attachments; each one must have a matching -n. Prefix with \"inline:\" (e.g. inline:logo.png) to embed it as an inline image referenced from the HTML body via cid:<name>. Overrides the %s environment variable", config.EnvAttachmentPath))
 
-	pflag.StringVarP(&args.attachmentName, "attachment_name", "n", "",
-		fmt.Sprintf("The name of the attachment file. Overrides the %s environment variable", config.EnvAttachmentName))
+	pflag.StringArrayVarP(&args.attachmentNames, "attachment_name", "n", nil,
+		fmt.Sprintf("The name of the attachment at the same position as -a. Overrides the %s environment variable", config.EnvAttachmentName))
+
+	pflag.BoolVarP(&args.serve, "serve", "", false,
+		fmt.Sprintf("Run as a long-lived HTTP API server instead of sending a single email. Requires the %s environment variable", config.EnvAPIToken))
+
+	pflag.StringVarP(&args.apiPort, "api_port", "p", "",
+		fmt.Sprintf("The port the API server listens on when --serve is set. Overrides the %s environment variable", config.EnvAPIPort))
+
+	pflag.StringVarP(&args.allowlistPath, "allowlist", "", "",
+		fmt.Sprintf("Path to a JSON allowlist of recipient addresses/domain globs. Overrides the %s environment variable", config.EnvAllowlistPath))
+
+	pflag.StringVarP(&args.blocklistPath, "blocklist", "", "",
+		fmt.Sprintf("Path to a JSON blocklist of recipient addresses/domain globs. Overrides the %s environment variable", config.EnvBlocklistPath))
+
+	pflag.BoolVarP(&args.dryRun, "dry-run", "", false,
+		fmt.Sprintf("Print what would be sent or blocked without actually drafting/sending/scheduling anything. Overrides the %s environment variable", config.EnvDryRun))
+
+	pflag.StringVarP(&args.saKeyPath, "sa-key", "", "",
+		fmt.Sprintf("Path to a Google service account JSON key. When set, authentication skips the interactive OAuth2 flow in favor of domain-wide delegation. Overrides the %s environment variable", config.EnvSAKeyPath))
+
+	pflag.StringVarP(&args.impersonateUser, "impersonate", "", "",
+		fmt.Sprintf("The Workspace user to impersonate when --sa-key is set. Required for domain-wide delegation. Overrides the %s environment variable", config.EnvImpersonateUser))
+
+	pflag.StringVarP(&args.actionLink, "action-link", "", "",
+		fmt.Sprintf("URL for an optional Gmail inline action button (schema.org ViewAction). Overrides the %s environment variable", config.EnvActionLink))
+
+	pflag.StringVarP(&args.actionName, "action-name", "", "",
+		fmt.Sprintf("Label for the --action-link button, e.g. \"View Details\". Overrides the %s environment variable", config.EnvActionName))
+
+	pflag.StringVarP(&args.actionDescription, "action-description", "", "",
+		fmt.Sprintf("Description shown alongside the --action-link button. Overrides the %s environment variable", config.EnvActionDescription))
+
+	pflag.StringVarP(&args.transport, "transport", "", "",
+		fmt.Sprintf("Which scheduler backend --schedule uses: streak or smtp. Overrides the %s environment variable", config.EnvTransport))
 
 	pflag.Parse()
 
@@ -100,6 +144,12 @@ func ParseArgs(args *Args) {
 		os.Exit(0)
 	}
 
+	// --serve runs the tool as an HTTP API server, which doesn't take the
+	// recruiter positional arguments
+	if args.serve {
+		return
+	}
+
 	// Handle positional arguments (recruiter_company, recruiter_name, recruiter_email)
 	if pflag.NArg() == 3 {
 		args.recruiterCompany = pflag.Arg(0)
@@ -112,6 +162,109 @@ func ParseArgs(args *Args) {
 
 }
 
+// IsServeMode reports whether --serve was passed.
+func IsServeMode(args *Args) bool {
+	return args.serve
+}
+
+// AttachmentPaths returns the attachment file paths passed via repeated -a
+// flags, falling back to a single comma-separated ATTACHMENT_PATH
+// value when no flags were given.
+func AttachmentPaths(args *Args) []string {
+	if len(args.attachmentPaths) > 0 {
+		return args.attachmentPaths
+	}
+	return splitEnvList(config.EnvAttachmentPath)
+}
+
+// AttachmentNames returns the attachment names passed via repeated -n
+// flags, falling back to a single comma-separated ATTACHMENT_NAME
+// value when no flags were given.
+func AttachmentNames(args *Args) []string {
+	if len(args.attachmentNames) > 0 {
+		return args.attachmentNames
+	}
+	return splitEnvList(config.EnvAttachmentName)
+}
+
+// PolicyPaths returns the configured allowlist/blocklist JSON paths.
+func PolicyPaths(args *Args) (allowlistPath, blocklistPath string) {
+	return GetArgOrEnv(args.allowlistPath, config.EnvAllowlistPath, false, ""),
+		GetArgOrEnv(args.blocklistPath, config.EnvBlocklistPath, false, "")
+}
+
+// IsDryRun reports whether --dry-run (or EMAILER_DRY_RUN) was set.
+func IsDryRun(args *Args) bool {
+	return GetBoolArgOrEnv(args.dryRun, config.EnvDryRun)
+}
+
+// RecruiterArgs returns the positional recruiter_company/recruiter_name/
+// recruiter_email arguments for the single-recipient CLI flow.
+func RecruiterArgs(args *Args) (company, name, email string) {
+	return args.recruiterCompany, args.recruiterName, args.recruiterEmail
+}
+
+// EmailContentArgs returns the raw --subject/--message_body_path flag
+// values, before resolving them against their environment variable/default.
+func EmailContentArgs(args *Args) (subject, messageBodyPath string) {
+	return args.subject, args.messageBodyPath
+}
+
+// AuthArgs returns the raw --token_path/--creds_path flag values.
+func AuthArgs(args *Args) (tokenPath, credsPath string) {
+	return args.tokenPath, args.credsPath
+}
+
+// APIPortArg returns the raw --api_port flag value.
+func APIPortArg(args *Args) string {
+	return args.apiPort
+}
+
+// SchedulingArgs returns whether --schedule was set, along with the raw
+// --email_address/--schedule_csv_path/--timezone flag values used to
+// configure Streak/SMTP scheduling.
+func SchedulingArgs(args *Args) (schedule bool, emailAddress, scheduleCsvPath, timezone string) {
+	return args.schedule, args.emailAddress, args.scheduleCsvPath, args.timezone
+}
+
+// ServiceAccountArgs returns the configured service account key path and the
+// Workspace user to impersonate, for domain-wide delegation auth. An empty
+// keyPath means the caller should fall back to the interactive OAuth2 flow.
+func ServiceAccountArgs(args *Args) (keyPath, impersonateUser string) {
+	return GetArgOrEnv(args.saKeyPath, config.EnvSAKeyPath, false, ""),
+		GetArgOrEnv(args.impersonateUser, config.EnvImpersonateUser, false, "")
+}
+
+// ActionArgs returns the configured Gmail inline action button link, name,
+// and description. An empty link means no ViewAction markup should be added.
+func ActionArgs(args *Args) (link, name, description string) {
+	return GetArgOrEnv(args.actionLink, config.EnvActionLink, false, ""),
+		GetArgOrEnv(args.actionName, config.EnvActionName, false, ""),
+		GetArgOrEnv(args.actionDescription, config.EnvActionDescription, false, "")
+}
+
+// Transport returns the configured scheduler backend ("streak" or "smtp"),
+// defaulting to "streak" to match the tool's original behavior.
+func Transport(args *Args) string {
+	return GetArgOrEnv(args.transport, config.EnvTransport, false, "streak")
+}
+
+func splitEnvList(envVar string) []string {
+	value := os.Getenv(envVar)
+	if value == "" {
+		return nil
+	}
+
+	parts := strings.Split(value, ",")
+	result := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if trimmed := strings.TrimSpace(part); trimmed != "" {
+			result = append(result, trimmed)
+		}
+	}
+	return result
+}
+
 // validateArgs validates that required arguments are not blank
 func ValidateArgs(args *Args) error {
 	if strings.TrimSpace(args.recruiterCompany) == "" {
@@ -125,15 +278,19 @@ func ValidateArgs(args *Args) error {
 	}
 
 	// Basic email validation
-	if !isValidEmail(args.recruiterEmail) {
+	if !IsValidEmail(args.recruiterEmail) {
 		return fmt.Errorf("invalid email format: %s", args.recruiterEmail)
 	}
 
+	if len(AttachmentPaths(args)) != len(AttachmentNames(args)) {
+		return fmt.Errorf("attachment_path and attachment_name must be provided the same number of times")
+	}
+
 	return nil
 }
 
-// isValidEmail performs basic email validation
-func isValidEmail(email string) bool {
+// IsValidEmail performs basic email validation
+func IsValidEmail(email string) bool {
 	email = strings.TrimSpace(email)
 	if email == "" {
 		return false
@@ -173,7 +330,9 @@ func GetArgOrEnv(argValue, envVar string, required bool, defaultValue string) st
 	return ""
 }
 
-func getBoolArgOrEnv(argValue bool, envVar string) bool {
+// GetBoolArgOrEnv resolves a boolean flag against its environment variable:
+// argValue wins if true, otherwise the env var is parsed, otherwise false.
+func GetBoolArgOrEnv(argValue bool, envVar string) bool {
 	if argValue {
 		return true
 	}
@@ -0,0 +1,206 @@
+// Package campaign supports bulk/outreach sends: parsing a recipients CSV
+// with per-row overrides, tracking resumable send state, and throttling.
+package campaign
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Row is a single recipient parsed from the recipients CSV. Company, Name,
+// and Email come from required columns; Subject, AttachmentPath, and
+// ScheduleCsvPath are optional per-row overrides of the campaign-wide
+// flags, InReplyToMessageID optionally marks the row as a follow-up touch
+// in a multi-touch sequence, and Extra holds any other columns for
+// template substitution.
+type Row struct {
+	Company         string
+	Name            string
+	Email           string
+	Subject         string
+	AttachmentPath  string
+	ScheduleCsvPath string
+	// InReplyToMessageID is the Message-ID (e.g. "<...@mail.gmail.com>") of
+	// an earlier message in this thread, from the "in_reply_to_message_id"
+	// CSV column. When set and the row is scheduled, the scheduled draft
+	// is recorded in a follow-up store so it's cancelled if the recipient
+	// replies to that earlier message first. See internal/imap.
+	InReplyToMessageID string
+	Extra              map[string]string
+}
+
+// ParseCSV reads the recipients CSV. The company/name/email columns are
+// required; subject/attachment_path/schedule_csv_path are optional per-row
+// overrides and in_reply_to_message_id an optional follow-up marker; any
+// other columns are exposed to the template as extra data.
+func ParseCSV(path string) ([]Row, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open recipients CSV: %v", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recipients CSV: %v", err)
+	}
+	if len(records) < 2 {
+		return nil, fmt.Errorf("recipients CSV must have a header row and at least one recipient")
+	}
+
+	header := records[0]
+	colIndex := map[string]int{}
+	for i, col := range header {
+		colIndex[strings.TrimSpace(col)] = i
+	}
+	for _, required := range []string{"company", "name", "email"} {
+		if _, ok := colIndex[required]; !ok {
+			return nil, fmt.Errorf("recipients CSV is missing required column %q", required)
+		}
+	}
+
+	overrideCols := map[string]bool{
+		"subject": true, "attachment_path": true, "schedule_csv_path": true,
+		"in_reply_to_message_id": true,
+	}
+
+	var rows []Row
+	for _, record := range records[1:] {
+		get := func(col string) string {
+			idx, ok := colIndex[col]
+			if !ok || idx >= len(record) {
+				return ""
+			}
+			return record[idx]
+		}
+
+		row := Row{
+			Company:            get("company"),
+			Name:               get("name"),
+			Email:              get("email"),
+			Subject:            get("subject"),
+			AttachmentPath:     get("attachment_path"),
+			ScheduleCsvPath:    get("schedule_csv_path"),
+			InReplyToMessageID: get("in_reply_to_message_id"),
+			Extra:              map[string]string{},
+		}
+		for col, idx := range colIndex {
+			if col == "company" || col == "name" || col == "email" || overrideCols[col] || idx >= len(record) {
+				continue
+			}
+			row.Extra[col] = record[idx]
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// Key returns the resumable-state key for a row: a hash of its full
+// content plus its email, so edited or reordered rows aren't mistaken for
+// ones already sent, while still being stable across re-runs of an
+// unmodified CSV.
+func Key(row Row) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s|%s|%s|%s|%s|%s|%s", row.Company, row.Name, row.Email, row.Subject, row.AttachmentPath, row.ScheduleCsvPath, row.InReplyToMessageID)
+	for _, k := range sortedKeys(row.Extra) {
+		fmt.Fprintf(h, "|%s=%s", k, row.Extra[k])
+	}
+	return fmt.Sprintf("%s:%s", row.Email, hex.EncodeToString(h.Sum(nil))[:16])
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	for i := 1; i < len(keys); i++ {
+		for j := i; j > 0 && keys[j-1] > keys[j]; j-- {
+			keys[j-1], keys[j] = keys[j], keys[j-1]
+		}
+	}
+	return keys
+}
+
+// Status tracks the outcome of sending to one row so a re-run of the same
+// campaign can skip rows that already went out.
+type Status struct {
+	Status       string `json:"status"` // queued, sent, failed, blocked
+	DraftID      string `json:"draft_id,omitempty"`
+	ScheduledFor string `json:"scheduled_for,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+// State is the resumable send state for a campaign, keyed by Key(row).
+type State map[string]*Status
+
+// LoadState reads the state file at path, returning an empty State if it
+// doesn't exist yet or fails to parse.
+func LoadState(path string) State {
+	state := State{}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return state
+	}
+
+	if err := json.Unmarshal(data, &state); err != nil {
+		log.Printf("Warning: failed to parse campaign state file, starting fresh: %v", err)
+		return State{}
+	}
+
+	return state
+}
+
+// Save persists state to path as indented JSON.
+func (s State) Save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal campaign state: %v", err)
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
+
+// ParseRate parses a throttle string like "30/hour" into the minimum delay
+// to wait between sends.
+func ParseRate(rate string) (time.Duration, error) {
+	if rate == "" {
+		return 0, nil
+	}
+
+	parts := strings.SplitN(rate, "/", 2)
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("invalid rate %q, expected format N/unit (e.g. 30/hour)", rate)
+	}
+
+	count, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || count <= 0 {
+		return 0, fmt.Errorf("invalid rate count %q", parts[0])
+	}
+
+	var window time.Duration
+	switch strings.ToLower(strings.TrimSpace(parts[1])) {
+	case "second", "sec", "s":
+		window = time.Second
+	case "minute", "min", "m":
+		window = time.Minute
+	case "hour", "hr", "h":
+		window = time.Hour
+	case "day", "d":
+		window = 24 * time.Hour
+	default:
+		return 0, fmt.Errorf("invalid rate unit %q", parts[1])
+	}
+
+	return window / time.Duration(count), nil
+}
@@ -4,13 +4,32 @@ package config
 const (
 	EnvEmailSubject           = "EMAIL_SUBJECT"
 	EnvMessageBodyPath        = "MESSAGE_BODY_PATH"
-	EnvTimezone              = "TIMEZONE"
-	EnvStreakToken           = "STREAK_TOKEN"
-	EnvStreakEmailAddress    = "STREAK_EMAIL_ADDRESS"
-	EnvScheduleCsvPath       = "SCHEDULE_CSV_PATH"
+	EnvTimezone               = "TIMEZONE"
+	EnvStreakToken            = "STREAK_TOKEN"
+	EnvStreakEmailAddress     = "STREAK_EMAIL_ADDRESS"
+	EnvScheduleCsvPath        = "SCHEDULE_CSV_PATH"
 	EnvEnableStreakScheduling = "ENABLE_STREAK_SCHEDULING"
-	EnvTokenPath             = "TOKEN_PATH"
-	EnvCredsPath             = "CREDS_PATH"
-	EnvAttachmentPath        = "ATTACHMENT_PATH"
-	EnvAttachmentName        = "ATTACHMENT_NAME"
+	EnvTokenPath              = "TOKEN_PATH"
+	EnvCredsPath              = "CREDS_PATH"
+	EnvAttachmentPath         = "ATTACHMENT_PATH"
+	EnvAttachmentName         = "ATTACHMENT_NAME"
+	EnvAPIToken               = "EMAILER_API_TOKEN"
+	EnvAPIPort                = "EMAILER_API_PORT"
+	EnvAllowlistPath          = "EMAILER_ALLOWLIST"
+	EnvBlocklistPath          = "EMAILER_BLOCKLIST"
+	EnvDryRun                 = "EMAILER_DRY_RUN"
+	EnvSAKeyPath              = "EMAILER_SA_KEY"
+	EnvImpersonateUser        = "EMAILER_IMPERSONATE"
+	EnvTokenSecret            = "EMAILER_TOKEN_SECRET"
+	EnvUnsubscribeBaseURL     = "EMAILER_UNSUBSCRIBE_BASE_URL"
+	EnvActionLink             = "EMAILER_ACTION_LINK"
+	EnvActionName             = "EMAILER_ACTION_NAME"
+	EnvActionDescription      = "EMAILER_ACTION_DESCRIPTION"
+	EnvTransport              = "EMAILER_TRANSPORT"
+	EnvSMTPHost               = "EMAILER_SMTP_HOST"
+	EnvSMTPPort               = "EMAILER_SMTP_PORT"
+	EnvSMTPUser               = "EMAILER_SMTP_USER"
+	EnvSMTPPassword           = "EMAILER_SMTP_PASSWORD"
+	EnvSMTPStartTLS           = "EMAILER_SMTP_STARTTLS"
+	EnvSMTPQueuePath          = "EMAILER_SMTP_QUEUE_PATH"
 )
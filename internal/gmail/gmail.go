@@ -2,7 +2,6 @@ package gmail
 
 import (
 	"context"
-	"encoding/base64"
 	"fmt"
 	"log"
 	"os"
@@ -34,15 +33,6 @@ type Draft struct {
 	Message *gmail.Message `json:"message"`
 }
 
-// EmailMessage represents an email message
-type EmailMessage struct {
-	To          string
-	Subject     string
-	Body        string
-	Attachment  []byte
-	AttachName  string
-}
-
 // Client represents a Gmail API client
 type Client struct {
 	service *gmail.Service
@@ -55,13 +45,13 @@ func NewClient() *Client {
 }
 
 // LoginWithToken authenticates using an existing token
-func (c *Client) LoginWithToken(token *Token) (*Credentials, error) {
+func (c *Client) LoginWithToken(token *Token, credsPath string) (*Credentials, error) {
 	// We need to read the credentials file to get the client config
-	credsData, err := os.ReadFile("credentials.json")
+	credsData, err := os.ReadFile(credsPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read credentials file: %v", err)
 	}
-	
+
 	config, err := google.ConfigFromJSON(credsData, gmail.GmailModifyScope)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create config: %v", err)
@@ -82,8 +72,22 @@ func (c *Client) LoginWithToken(token *Token) (*Credentials, error) {
 	c.service = service
 	c.config = config
 
+	// Get a current token from the token source now (it refreshes the token
+	// if it has expired) rather than returning the stored one as-is: callers that just save it back to token.json (or hand its
+	// AccessToken to something outside this client, like IMAP) need the
+	// live value, not whatever was last on disk.
+	refreshed, err := tokenSource.Token()
+	if err != nil {
+		return nil, fmt.Errorf("failed to refresh token: %v", err)
+	}
+
 	return &Credentials{
-		Token:  token,
+		Token: &Token{
+			AccessToken:  refreshed.AccessToken,
+			TokenType:    refreshed.TokenType,
+			RefreshToken: token.RefreshToken,
+			Expiry:       refreshed.Expiry,
+		},
 		Config: config,
 	}, nil
 }
@@ -137,23 +141,42 @@ func (c *Client) LoginWithCredentials(credsPath string) (*Credentials, error) {
 	}, nil
 }
 
-// CreateEmailMessage creates an email message
-func CreateEmailMessage(body, to, subject string, attachment []byte, attachmentName string) *gmail.Message {
-	message := &gmail.Message{}
-	
-	// Create email content
-	emailContent := fmt.Sprintf("To: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/html; charset=UTF-8\r\n\r\n%s", 
-		to, subject, body)
-	
-	if attachment != nil && attachmentName != "" {
-		// Add attachment
-		boundary := "boundary123"
-		emailContent = fmt.Sprintf("To: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\nContent-Type: multipart/mixed; boundary=%s\r\n\r\n--%s\r\nContent-Type: text/html; charset=UTF-8\r\n\r\n%s\r\n--%s\r\nContent-Type: application/octet-stream; name=\"%s\"\r\nContent-Transfer-Encoding: base64\r\nContent-Disposition: attachment; filename=\"%s\"\r\n\r\n%s\r\n--%s--",
-			to, subject, boundary, boundary, body, boundary, attachmentName, attachmentName, base64.StdEncoding.EncodeToString(attachment), boundary)
+// LoginWithServiceAccount authenticates as a Workspace service account key,
+// impersonating impersonateUser via domain-wide delegation. This avoids the
+// interactive OAuth2 flow entirely, so it's suitable for headless/CI use.
+func (c *Client) LoginWithServiceAccount(keyPath, impersonateUser string) (*Credentials, error) {
+	ctx := context.Background()
+
+	keyData, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account key: %v", err)
 	}
-	
-	message.Raw = base64.URLEncoding.EncodeToString([]byte(emailContent))
-	return message
+
+	jwtConfig, err := google.JWTConfigFromJSON(keyData, gmail.GmailSendScope, gmail.GmailModifyScope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse service account key: %v", err)
+	}
+	jwtConfig.Subject = impersonateUser
+
+	service, err := gmail.NewService(ctx, option.WithTokenSource(jwtConfig.TokenSource(ctx)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create service: %v", err)
+	}
+
+	c.service = service
+
+	token, err := jwtConfig.TokenSource(ctx).Token()
+	if err != nil {
+		return nil, fmt.Errorf("failed to mint service account token: %v", err)
+	}
+
+	return &Credentials{
+		Token: &Token{
+			AccessToken: token.AccessToken,
+			TokenType:   token.TokenType,
+			Expiry:      token.Expiry,
+		},
+	}, nil
 }
 
 // SaveDraft saves a draft message
@@ -175,6 +198,30 @@ func (c *Client) SaveDraft(message *gmail.Message) (*Draft, error) {
 	}, nil
 }
 
+// GetDraft fetches a draft by ID, including its message's thread ID.
+func (c *Client) GetDraft(draftID string) (*Draft, error) {
+	result, err := c.service.Users.Drafts.Get("me", draftID).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get draft: %v", err)
+	}
+
+	return &Draft{
+		Id:      result.Id,
+		Message: result.Message,
+	}, nil
+}
+
+// DeleteDraft deletes a draft by ID. It's used to cancel a scheduled
+// follow-up once a reply to the original message has been detected.
+func (c *Client) DeleteDraft(draftID string) error {
+	if err := c.service.Users.Drafts.Delete("me", draftID).Do(); err != nil {
+		return fmt.Errorf("failed to delete draft: %v", err)
+	}
+
+	log.Printf("Draft deleted: %s", draftID)
+	return nil
+}
+
 // SendNow sends a message immediately
 func (c *Client) SendNow(message *gmail.Message) (*gmail.Message, error) {
 	result, err := c.service.Users.Messages.Send("me", message).Do()
@@ -0,0 +1,332 @@
+package gmail
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"emailer/internal/config"
+	"emailer/internal/tokens"
+
+	"google.golang.org/api/gmail/v1"
+)
+
+// maxMessageBytes is Gmail's limit on a raw RFC 5322 message, including any
+// attachments. See https://developers.google.com/gmail/api/reference/rest/v1/users.messages/send.
+const maxMessageBytes = 25 * 1024 * 1024
+
+// inlineAttachmentPrefix marks an attachment path as an inline image (e.g.
+// "inline:logo.png") rather than a regular attachment, so HTML bodies can
+// reference it via <img src="cid:...">.
+const inlineAttachmentPrefix = "inline:"
+
+// Attachment represents a single MIME attachment or inline resource
+// carried by an EmailMessage. Inline attachments are referenced from HTML
+// bodies via `<img src="cid:...">`, where `cid` matches ContentID.
+type Attachment struct {
+	Name        string
+	ContentType string
+	Data        []byte
+	Inline      bool
+	ContentID   string
+}
+
+// SplitAttachmentPath strips an optional "inline:" prefix from an
+// --attachment_path value, reporting whether it was present.
+func SplitAttachmentPath(path string) (cleanPath string, inline bool) {
+	if strings.HasPrefix(path, inlineAttachmentPrefix) {
+		return strings.TrimPrefix(path, inlineAttachmentPrefix), true
+	}
+	return path, false
+}
+
+// NewAttachment builds an Attachment from already-read file contents. When
+// inline is set, it's given a Content-ID (derived from name) so it can be
+// referenced from the HTML body via cid:name instead of showing up as a
+// regular attachment.
+func NewAttachment(name string, data []byte, inline bool) Attachment {
+	att := Attachment{Name: name, Data: data, Inline: inline}
+	if inline {
+		att.ContentID = name
+	}
+	return att
+}
+
+// ViewAction describes a Gmail inline action button, rendered into the HTML
+// body as schema.org EmailMessage/ViewAction markup.
+type ViewAction struct {
+	Link        string
+	Name        string
+	Description string
+}
+
+// EmailMessage describes the content of an outgoing email before it is
+// assembled into a Gmail API message.
+type EmailMessage struct {
+	To      string
+	Subject string
+	Body    string // HTML body; a plain-text alternative is generated automatically unless Text is set
+	// Text, if set, is used as the explicit plain-text alternative instead
+	// of deriving one from Body.
+	Text        string
+	Attachments []Attachment
+	// Headers carries additional top-level headers, e.g. List-Unsubscribe.
+	Headers map[string]string
+	// Action, if set with a non-empty Link, prepends schema.org ViewAction
+	// markup to Body so Gmail can show an inline action button.
+	Action *ViewAction
+}
+
+// MessageBuilder assembles a MIME message: a multipart/alternative body
+// (auto-generated plain text plus HTML) optionally wrapped in
+// multipart/mixed alongside any attachments or inline images.
+type MessageBuilder struct {
+	To          string
+	Subject     string
+	HTML        string
+	Text        string // optional explicit plain-text fallback; derived from HTML when empty
+	Attachments []Attachment
+	// Headers carries additional top-level headers, e.g. List-Unsubscribe.
+	Headers map[string]string
+}
+
+// Build assembles the MIME message and returns it as a gmail.Message ready
+// for Client.SaveDraft/SendNow.
+func (b *MessageBuilder) Build() (*gmail.Message, error) {
+	var buf bytes.Buffer
+
+	altBuf := &bytes.Buffer{}
+	altWriter := multipart.NewWriter(altBuf)
+
+	text := b.Text
+	if text == "" {
+		text = htmlToPlainText(b.HTML)
+	}
+
+	if err := writeTextPart(altWriter, "text/plain", text); err != nil {
+		return nil, fmt.Errorf("failed to write plain text part: %v", err)
+	}
+	if err := writeTextPart(altWriter, "text/html", b.HTML); err != nil {
+		return nil, fmt.Errorf("failed to write HTML part: %v", err)
+	}
+	if err := altWriter.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close alternative part: %v", err)
+	}
+
+	fmt.Fprintf(&buf, "To: %s\r\n", b.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", b.Subject))
+	for key, value := range b.Headers {
+		fmt.Fprintf(&buf, "%s: %s\r\n", key, value)
+	}
+	fmt.Fprintf(&buf, "MIME-Version: 1.0\r\n")
+
+	if len(b.Attachments) == 0 {
+		// No attachments: the alternative part *is* the whole message body.
+		fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", altWriter.Boundary())
+		buf.Write(altBuf.Bytes())
+	} else {
+		// The mixed boundary has to be known before we can write the
+		// top-level Content-Type header, so build the mixed part into a
+		// scratch buffer first and append it once the boundary is fixed.
+		var body bytes.Buffer
+		mixedWriter := multipart.NewWriter(&body)
+
+		part, err := mixedWriter.CreatePart(textproto.MIMEHeader{
+			"Content-Type": {fmt.Sprintf("multipart/alternative; boundary=%s", altWriter.Boundary())},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to write message body: %v", err)
+		}
+		if _, err := part.Write(altBuf.Bytes()); err != nil {
+			return nil, fmt.Errorf("failed to write message body: %v", err)
+		}
+
+		for _, att := range b.Attachments {
+			if err := writeAttachmentPart(mixedWriter, att); err != nil {
+				return nil, fmt.Errorf("failed to write attachment %q: %v", att.Name, err)
+			}
+		}
+
+		if err := mixedWriter.Close(); err != nil {
+			return nil, fmt.Errorf("failed to finalize MIME message: %v", err)
+		}
+
+		fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", mixedWriter.Boundary())
+		buf.Write(body.Bytes())
+	}
+
+	if buf.Len() > maxMessageBytes {
+		return nil, fmt.Errorf("message is %d bytes, which exceeds Gmail's %d byte (25MB) limit", buf.Len(), maxMessageBytes)
+	}
+
+	return &gmail.Message{
+		Raw: base64.URLEncoding.EncodeToString(buf.Bytes()),
+	}, nil
+}
+
+// detectContentType guesses a MIME type from the attachment's file
+// extension, falling back to content sniffing for extensions Go's mime
+// package doesn't recognize (or files with none at all).
+func detectContentType(name string, data []byte) string {
+	if ext := filepath.Ext(name); ext != "" {
+		if ct := mime.TypeByExtension(ext); ct != "" {
+			return ct
+		}
+	}
+	return http.DetectContentType(data)
+}
+
+func writeTextPart(w *multipart.Writer, contentType, content string) error {
+	partWriter, err := w.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType + "; charset=UTF-8"},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return err
+	}
+
+	qpWriter := quotedprintable.NewWriter(partWriter)
+	if _, err := qpWriter.Write([]byte(content)); err != nil {
+		return err
+	}
+	return qpWriter.Close()
+}
+
+func writeAttachmentPart(w *multipart.Writer, att Attachment) error {
+	contentType := att.ContentType
+	if contentType == "" {
+		contentType = detectContentType(att.Name, att.Data)
+	}
+
+	disposition := "attachment"
+	if att.Inline {
+		disposition = "inline"
+	}
+
+	header := textproto.MIMEHeader{
+		"Content-Type":              {fmt.Sprintf("%s; name=%q", contentType, att.Name)},
+		"Content-Transfer-Encoding": {"base64"},
+		"Content-Disposition":       {fmt.Sprintf("%s; filename=%q", disposition, att.Name)},
+	}
+	if att.ContentID != "" {
+		header.Set("Content-ID", fmt.Sprintf("<%s>", att.ContentID))
+	}
+
+	partWriter, err := w.CreatePart(header)
+	if err != nil {
+		return err
+	}
+
+	encoder := base64.NewEncoder(base64.StdEncoding, partWriter)
+	if _, err := encoder.Write(att.Data); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+var (
+	htmlTagPattern        = regexp.MustCompile(`<[^>]*>`)
+	htmlWhitespacePattern = regexp.MustCompile(`[ \t]+`)
+)
+
+// htmlToPlainText generates a best-effort plain-text fallback from an HTML
+// body by stripping tags, unescaping the handful of entities we commonly
+// emit, and collapsing whitespace.
+func htmlToPlainText(html string) string {
+	text := htmlTagPattern.ReplaceAllString(html, "")
+	replacer := strings.NewReplacer("&nbsp;", " ", "&amp;", "&", "&lt;", "<", "&gt;", ">", "&quot;", `"`)
+	text = replacer.Replace(text)
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = htmlWhitespacePattern.ReplaceAllString(strings.TrimSpace(line), " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// BuildMessage assembles an EmailMessage, including any number of
+// attachments and inline images, into a gmail.Message.
+func BuildMessage(msg *EmailMessage) (*gmail.Message, error) {
+	html := msg.Body
+	if msg.Action != nil && msg.Action.Link != "" {
+		html = renderViewAction(msg.Action) + html
+	}
+
+	builder := &MessageBuilder{
+		To:          msg.To,
+		Subject:     msg.Subject,
+		HTML:        html,
+		Text:        msg.Text,
+		Attachments: msg.Attachments,
+		Headers:     msg.Headers,
+	}
+	return builder.Build()
+}
+
+// renderViewAction renders the schema.org EmailMessage/ViewAction microdata
+// Gmail looks for to show an inline action button next to the subject line.
+// It's inert markup (a hidden div), so it's safe to prepend to any HTML body.
+func renderViewAction(action *ViewAction) string {
+	var b strings.Builder
+	b.WriteString(`<div itemscope itemtype="http://schema.org/EmailMessage">`)
+	b.WriteString(`<div itemprop="potentialAction" itemscope itemtype="http://schema.org/ViewAction">`)
+	fmt.Fprintf(&b, `<link itemprop="url" href=%q/>`, action.Link)
+	if action.Name != "" {
+		fmt.Fprintf(&b, `<meta itemprop="name" content=%q/>`, action.Name)
+	}
+	b.WriteString(`</div>`)
+	if action.Description != "" {
+		fmt.Fprintf(&b, `<meta itemprop="description" content=%q/>`, action.Description)
+	}
+	b.WriteString(`</div>`)
+	return b.String()
+}
+
+// unsubscribeExtras builds a List-Unsubscribe header value and a footer
+// link for to, if EMAILER_TOKEN_SECRET and EMAILER_UNSUBSCRIBE_BASE_URL are
+// both configured. It returns empty strings otherwise, so campaigns that
+// haven't set those up keep sending unmodified mail.
+func unsubscribeExtras(to string) (header, footer string) {
+	secret := os.Getenv(config.EnvTokenSecret)
+	baseURL := os.Getenv(config.EnvUnsubscribeBaseURL)
+	if secret == "" || baseURL == "" {
+		return "", ""
+	}
+
+	token := tokens.Generate([]byte(secret), to, tokens.PurposeUnsubscribe)
+	link := fmt.Sprintf("%s?t=%s", strings.TrimRight(baseURL, "/"), token)
+
+	return fmt.Sprintf("<%s>", link),
+		fmt.Sprintf("<br><br><hr><small>To stop receiving these emails, <a href=\"%s\">unsubscribe</a>.</small>", link)
+}
+
+// CreateEmailMessage builds a gmail.Message from an HTML body and any
+// number of attachments. It's a convenience wrapper around MessageBuilder
+// for callers that don't need the rest of EmailMessage (Text, Action, ...).
+// It returns BuildMessage's error rather than falling back to a message
+// missing its attachments, so callers don't silently send or draft
+// something other than what was asked for.
+func CreateEmailMessage(body, to, subject string, attachments []Attachment) (*gmail.Message, error) {
+	msg := &EmailMessage{
+		To:          to,
+		Subject:     subject,
+		Body:        body,
+		Attachments: attachments,
+	}
+
+	if listUnsubscribe, footer := unsubscribeExtras(to); listUnsubscribe != "" {
+		msg.Headers = map[string]string{"List-Unsubscribe": listUnsubscribe}
+		msg.Body += footer
+	}
+
+	return BuildMessage(msg)
+}
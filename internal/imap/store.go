@@ -0,0 +1,84 @@
+package imap
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// FollowUp is a scheduled draft that should be cancelled if the recipient
+// replies to the message it follows up on.
+type FollowUp struct {
+	Recipient string `json:"recipient"`
+	DraftID   string `json:"draft_id"`
+	ThreadID  string `json:"thread_id,omitempty"`
+}
+
+// Store is a local JSON file mapping a sent message's Message-ID to the
+// scheduled follow-up that should be cancelled if its recipient replies.
+type Store struct {
+	path    string
+	mu      sync.Mutex
+	entries map[string]FollowUp
+}
+
+// OpenStore loads the store from path, creating an empty one if it doesn't
+// exist yet.
+func OpenStore(path string) (*Store, error) {
+	store := &Store{path: path, entries: map[string]FollowUp{}}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return store, nil
+		}
+		return nil, fmt.Errorf("failed to read follow-up store: %v", err)
+	}
+
+	if err := json.Unmarshal(data, &store.entries); err != nil {
+		return nil, fmt.Errorf("failed to parse follow-up store: %v", err)
+	}
+
+	return store, nil
+}
+
+// Put records a follow-up draft keyed by the Message-ID of the message it
+// follows up on.
+func (s *Store) Put(messageID string, followUp FollowUp) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries[messageID] = followUp
+	return s.save()
+}
+
+// Take removes and returns the follow-up tracked for messageID, if any.
+func (s *Store) Take(messageID string) (FollowUp, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	followUp, ok := s.entries[messageID]
+	if !ok {
+		return FollowUp{}, false
+	}
+
+	delete(s.entries, messageID)
+	if err := s.save(); err != nil {
+		// The in-memory entry is already gone; a failed write just means
+		// we might double-process this message-id on restart, which
+		// handleReply already tolerates via the same Take call.
+		return followUp, true
+	}
+
+	return followUp, true
+}
+
+func (s *Store) save() error {
+	data, err := json.MarshalIndent(s.entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal follow-up store: %v", err)
+	}
+
+	return os.WriteFile(s.path, data, 0644)
+}
@@ -0,0 +1,251 @@
+// Package imap watches a Gmail inbox over IMAP for replies to previously
+// sent messages, so multi-touch outreach can cancel a follow-up that's
+// scheduled to go out to someone who already responded.
+package imap
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"emailer/internal/gmail"
+
+	"github.com/emersion/go-imap/v2"
+	"github.com/emersion/go-imap/v2/imapclient"
+	"github.com/emersion/go-sasl"
+)
+
+const serverAddr = "imap.gmail.com:993"
+
+// xoauth2Client implements sasl.Client for Gmail's SASL XOAUTH2 mechanism.
+// go-sasl doesn't ship this one (it predates Gmail's OAuth2 IMAP support),
+// so it's implemented here per Google's spec: a single initial response of
+// "user=<email>\x01auth=Bearer <token>\x01\x01", no further challenges.
+// See https://developers.google.com/gmail/imap/xoauth2-protocol.
+type xoauth2Client struct {
+	username    string
+	accessToken string
+}
+
+// newXoauth2Client returns a sasl.Client that authenticates username via
+// Gmail's XOAUTH2 mechanism using accessToken.
+func newXoauth2Client(username, accessToken string) sasl.Client {
+	return &xoauth2Client{username: username, accessToken: accessToken}
+}
+
+func (c *xoauth2Client) Start() (mech string, ir []byte, err error) {
+	ir = []byte(fmt.Sprintf("user=%s\x01auth=Bearer %s\x01\x01", c.username, c.accessToken))
+	return "XOAUTH2", ir, nil
+}
+
+// Next is only called if the server rejects the initial response with a
+// (non-fatal) continuation, which XOAUTH2 uses to carry a JSON error
+// payload. Responding with an empty message lets the server return the
+// real authentication failure instead of hanging.
+func (c *xoauth2Client) Next(challenge []byte) (response []byte, err error) {
+	return []byte{}, nil
+}
+
+// Dial connects to Gmail's IMAP server and authenticates with the same
+// OAuth2 access token used for the Gmail API, via SASL XOAUTH2.
+func Dial(username, accessToken string) (*imapclient.Client, error) {
+	client, err := imapclient.DialTLS(serverAddr, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %v", serverAddr, err)
+	}
+
+	if err := client.Authenticate(newXoauth2Client(username, accessToken)); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("XOAUTH2 authentication failed: %v", err)
+	}
+
+	return client, nil
+}
+
+// Watcher polls INBOX for new messages, extracts In-Reply-To/References
+// headers, and cancels any scheduled follow-up tracked in Store for a
+// message those headers reference.
+type Watcher struct {
+	imapClient  *imapclient.Client
+	gmailClient *gmail.Client
+	store       *Store
+
+	// PollInterval is how long Run waits between scans of INBOX, whether
+	// or not the server supports IDLE.
+	PollInterval time.Duration
+}
+
+// NewWatcher returns a Watcher that cancels follow-ups recorded in store.
+func NewWatcher(imapClient *imapclient.Client, gmailClient *gmail.Client, store *Store) *Watcher {
+	return &Watcher{
+		imapClient:   imapClient,
+		gmailClient:  gmailClient,
+		store:        store,
+		PollInterval: time.Minute,
+	}
+}
+
+// Run polls INBOX once immediately, then polls it again every PollInterval
+// (holding an IDLE command open while it waits) until ctx is cancelled.
+func (w *Watcher) Run(ctx context.Context) error {
+	if _, err := w.imapClient.Select("INBOX", nil).Wait(); err != nil {
+		return fmt.Errorf("failed to select INBOX: %v", err)
+	}
+
+	if err := w.pollOnce(); err != nil {
+		log.Printf("Warning: initial reply scan failed: %v", err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		if err := w.waitForActivity(ctx); err != nil {
+			log.Printf("Warning: IDLE failed, falling back to polling: %v", err)
+		}
+
+		if err := w.pollOnce(); err != nil {
+			log.Printf("Warning: reply scan failed: %v", err)
+		}
+	}
+}
+
+// waitForActivity blocks until the poll interval elapses or ctx is
+// cancelled, keeping an IDLE command open meanwhile. New-mail notifications
+// from the server do not end the wait early. If IDLE can't be started, it
+// still waits out the interval and then returns that error.
+func (w *Watcher) waitForActivity(ctx context.Context) error {
+	idleCmd, err := w.imapClient.Idle()
+	if err == nil {
+		defer idleCmd.Close()
+	}
+
+	timer := time.NewTimer(w.PollInterval)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+	return err
+}
+
+// pollOnce fetches unseen messages, checks whether they reply to a
+// message we're tracking, and cancels the matching follow-up if so.
+func (w *Watcher) pollOnce() error {
+	searchData, err := w.imapClient.Search(&imap.SearchCriteria{
+		NotFlag: []imap.Flag{imap.FlagSeen},
+	}, nil).Wait()
+	if err != nil {
+		return fmt.Errorf("failed to search INBOX: %v", err)
+	}
+
+	seqNums := searchData.AllSeqNums()
+	if len(seqNums) == 0 {
+		return nil
+	}
+
+	fetchOptions := &imap.FetchOptions{
+		Envelope: true,
+		BodySection: []*imap.FetchItemBodySection{
+			{Specifier: imap.PartSpecifierHeader},
+		},
+	}
+
+	fetchCmd := w.imapClient.Fetch(imap.SeqSetNum(seqNums...), fetchOptions)
+	defer fetchCmd.Close()
+
+	for {
+		msg := fetchCmd.Next()
+		if msg == nil {
+			break
+		}
+
+		buf, err := msg.Collect()
+		if err != nil {
+			log.Printf("Warning: failed to fetch message: %v", err)
+			continue
+		}
+
+		for _, referenced := range referencedMessageIDs(buf) {
+			if err := w.handleReply(referenced); err != nil {
+				log.Printf("Warning: failed to cancel follow-up for %s: %v", referenced, err)
+			}
+		}
+	}
+
+	return fetchCmd.Close()
+}
+
+// handleReply cancels the scheduled follow-up draft tracked for
+// messageID, if there is one.
+func (w *Watcher) handleReply(messageID string) error {
+	followUp, ok := w.store.Take(messageID)
+	if !ok {
+		return nil
+	}
+
+	if err := w.gmailClient.DeleteDraft(followUp.DraftID); err != nil {
+		return err
+	}
+
+	log.Printf("Reply detected from %s, cancelled follow-up draft %s", followUp.Recipient, followUp.DraftID)
+	return nil
+}
+
+// referencedMessageIDs extracts the Message-IDs a fetched message's
+// In-Reply-To and References headers point at.
+func referencedMessageIDs(msg *imapclient.FetchMessageBuffer) []string {
+	var ids []string
+	seen := map[string]bool{}
+
+	for _, section := range msg.BodySection {
+		header := string(section.Bytes)
+		for _, field := range []string{"In-Reply-To", "References"} {
+			for _, id := range extractMessageIDs(header, field) {
+				if !seen[id] {
+					seen[id] = true
+					ids = append(ids, id)
+				}
+			}
+		}
+	}
+
+	return ids
+}
+
+// extractMessageIDs pulls the `<...>` message-id tokens out of the value of
+// the given header field in rawHeader, including its folded continuation
+// lines.
+func extractMessageIDs(rawHeader, field string) []string {
+	var ids []string
+
+	inField := false
+	for _, line := range strings.Split(rawHeader, "\r\n") {
+		var value string
+		switch {
+		case strings.HasPrefix(strings.ToLower(line), strings.ToLower(field)+":"):
+			inField = true
+			value = line[len(field)+1:]
+		case inField && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")):
+			value = line
+		default:
+			inField = false
+			continue
+		}
+
+		for _, token := range strings.Fields(value) {
+			token = strings.TrimSpace(token)
+			if strings.HasPrefix(token, "<") && strings.HasSuffix(token, ">") {
+				ids = append(ids, token)
+			}
+		}
+	}
+
+	return ids
+}
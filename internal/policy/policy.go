@@ -0,0 +1,148 @@
+// Package policy enforces a recipient allowlist/blocklist so a
+// misconfigured campaign or API call can't send mail to disallowed
+// addresses or domains.
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// list holds the exact addresses and domain/address globs loaded from one
+// allowlist or blocklist file.
+type list struct {
+	Addresses []string `json:"addresses"`
+	Globs     []string `json:"globs"`
+}
+
+func (l *list) matches(address string) (bool, string) {
+	if l == nil {
+		return false, ""
+	}
+
+	address = strings.ToLower(strings.TrimSpace(address))
+
+	for _, exact := range l.Addresses {
+		if strings.ToLower(strings.TrimSpace(exact)) == address {
+			return true, fmt.Sprintf("exact match %q", exact)
+		}
+	}
+
+	domain := ""
+	if idx := strings.LastIndex(address, "@"); idx != -1 {
+		domain = address[idx+1:]
+	}
+
+	for _, pattern := range l.Globs {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if ok, _ := filepath.Match(pattern, address); ok {
+			return true, fmt.Sprintf("glob %q", pattern)
+		}
+		if domain != "" {
+			if ok, _ := filepath.Match(pattern, domain); ok {
+				return true, fmt.Sprintf("domain glob %q", pattern)
+			}
+		}
+	}
+
+	return false, ""
+}
+
+func loadList(path string) (*list, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+
+	l := &list{}
+	if err := json.Unmarshal(data, l); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+
+	return l, nil
+}
+
+// AppendToBlocklist adds address to the blocklist JSON file at path,
+// creating the file if it doesn't exist yet. It's a no-op if the address is
+// already present, so it's safe to call repeatedly (e.g. from an
+// unsubscribe handler hit more than once).
+func AppendToBlocklist(path, address string) error {
+	if path == "" {
+		return fmt.Errorf("no blocklist path configured")
+	}
+
+	l, err := loadList(path)
+	if err != nil {
+		return err
+	}
+	if l == nil {
+		l = &list{}
+	}
+
+	address = strings.ToLower(strings.TrimSpace(address))
+	for _, existing := range l.Addresses {
+		if strings.ToLower(strings.TrimSpace(existing)) == address {
+			return nil
+		}
+	}
+	l.Addresses = append(l.Addresses, address)
+
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal blocklist: %v", err)
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
+
+// Policy gates outgoing recipients against an optional allowlist and
+// blocklist.
+type Policy struct {
+	allow  *list
+	block  *list
+	DryRun bool
+}
+
+// Load reads the allowlist and blocklist JSON files, each of the form
+// {"addresses": ["a@b.com"], "globs": ["*.edu", "noreply@*"]}. Either path
+// may be empty to skip that list.
+func Load(allowlistPath, blocklistPath string, dryRun bool) (*Policy, error) {
+	allow, err := loadList(allowlistPath)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := loadList(blocklistPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Policy{allow: allow, block: block, DryRun: dryRun}, nil
+}
+
+// Check rejects a recipient that's on the blocklist, or, when an allowlist
+// is configured, one that isn't on it. Every rejection is logged with the
+// reason it was rejected.
+func (p *Policy) Check(recipient string) error {
+	if ok, reason := p.block.matches(recipient); ok {
+		log.Printf("Policy rejected %s: blocklisted (%s)", recipient, reason)
+		return fmt.Errorf("%s is blocklisted (%s)", recipient, reason)
+	}
+
+	if p.allow != nil {
+		if ok, _ := p.allow.matches(recipient); !ok {
+			log.Printf("Policy rejected %s: not on allowlist", recipient)
+			return fmt.Errorf("%s is not on the allowlist", recipient)
+		}
+	}
+
+	return nil
+}
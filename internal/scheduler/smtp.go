@@ -0,0 +1,200 @@
+package scheduler
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"os"
+	"sync"
+	"time"
+)
+
+// SMTPConfig holds the connection details for a self-hosted SMTP relay.
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	StartTLS bool
+}
+
+func (c SMTPConfig) addr() string {
+	return fmt.Sprintf("%s:%d", c.Host, c.Port)
+}
+
+// PendingSend is one queued message waiting for its scheduled send time.
+type PendingSend struct {
+	To     string    `json:"to"`
+	Raw    string    `json:"raw"` // base64-encoded RFC 5322 message
+	SendAt time.Time `json:"send_at"`
+}
+
+// SMTPScheduler implements Scheduler without depending on Streak: instead
+// of scheduling the Gmail draft, it appends a pending send to an on-disk
+// JSON queue. A separate `emailer smtp-daemon` process (see RunDaemon)
+// polls the queue and delivers each message over SMTP once it's due.
+type SMTPScheduler struct {
+	Config    SMTPConfig
+	QueuePath string
+
+	mu sync.Mutex
+}
+
+// ScheduleDraft implements Scheduler by enqueueing req for later delivery.
+func (s *SMTPScheduler) ScheduleDraft(ctx context.Context, req *ScheduleRequest, sendTime time.Time) error {
+	if len(req.Raw) == 0 {
+		return fmt.Errorf("scheduling error: no raw message available for SMTP transport")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	queue, err := loadQueue(s.QueuePath)
+	if err != nil {
+		return err
+	}
+
+	queue = append(queue, PendingSend{
+		To:     req.ToAddress,
+		Raw:    base64.StdEncoding.EncodeToString(req.Raw),
+		SendAt: sendTime,
+	})
+
+	return saveQueue(s.QueuePath, queue)
+}
+
+func loadQueue(path string) ([]PendingSend, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SMTP queue: %v", err)
+	}
+
+	var queue []PendingSend
+	if err := json.Unmarshal(data, &queue); err != nil {
+		return nil, fmt.Errorf("failed to parse SMTP queue: %v", err)
+	}
+	return queue, nil
+}
+
+func saveQueue(path string, queue []PendingSend) error {
+	data, err := json.MarshalIndent(queue, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal SMTP queue: %v", err)
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// RunDaemon polls the on-disk queue at queuePath and delivers any message
+// whose SendAt has arrived, removing it from the queue once sent. It blocks
+// until ctx is cancelled.
+func RunDaemon(ctx context.Context, queuePath string, cfg SMTPConfig, pollInterval time.Duration) error {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := processDue(queuePath, cfg); err != nil {
+			log.Printf("SMTP daemon: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func processDue(queuePath string, cfg SMTPConfig) error {
+	queue, err := loadQueue(queuePath)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var remaining []PendingSend
+	for _, pending := range queue {
+		if pending.SendAt.After(now) {
+			remaining = append(remaining, pending)
+			continue
+		}
+
+		raw, err := base64.StdEncoding.DecodeString(pending.Raw)
+		if err != nil {
+			log.Printf("SMTP daemon: dropping malformed queued message to %s: %v", pending.To, err)
+			continue
+		}
+
+		if err := sendSMTP(cfg, pending.To, raw); err != nil {
+			log.Printf("SMTP daemon: failed to send to %s, will retry: %v", pending.To, err)
+			remaining = append(remaining, pending)
+			continue
+		}
+
+		log.Printf("SMTP daemon: sent queued message to %s", pending.To)
+	}
+
+	return saveQueue(queuePath, remaining)
+}
+
+// sendSMTP delivers raw (an RFC 5322 message) to "to" via cfg, using
+// STARTTLS when cfg.StartTLS is set.
+func sendSMTP(cfg SMTPConfig, to string, raw []byte) error {
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+
+	if !cfg.StartTLS {
+		return smtp.SendMail(cfg.addr(), auth, cfg.Username, []string{to}, raw)
+	}
+
+	conn, err := net.Dial("tcp", cfg.addr())
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %v", err)
+	}
+	defer conn.Close()
+
+	client, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		return fmt.Errorf("failed to create SMTP client: %v", err)
+	}
+	defer client.Close()
+
+	if err := client.StartTLS(&tls.Config{ServerName: cfg.Host}); err != nil {
+		return fmt.Errorf("STARTTLS failed: %v", err)
+	}
+
+	if auth != nil {
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("SMTP auth failed: %v", err)
+		}
+	}
+
+	if err := client.Mail(cfg.Username); err != nil {
+		return fmt.Errorf("MAIL FROM failed: %v", err)
+	}
+	if err := client.Rcpt(to); err != nil {
+		return fmt.Errorf("RCPT TO failed: %v", err)
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("DATA failed: %v", err)
+	}
+	if _, err := w.Write(raw); err != nil {
+		return fmt.Errorf("failed to write message body: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to finalize message: %v", err)
+	}
+
+	return client.Quit()
+}
@@ -0,0 +1,54 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"emailer/internal/gmail"
+	"emailer/internal/streak"
+)
+
+// ScheduleRequest carries everything a Scheduler implementation needs to
+// send a drafted email at a later time, regardless of which transport it
+// goes out over.
+type ScheduleRequest struct {
+	Draft        *gmail.Draft
+	ToAddress    string
+	Subject      string
+	EmailAddress string
+	// Raw is the RFC 5322 message as originally built for the draft
+	// (gmail.Message.Raw decoded from base64, not the encoded string). Only the SMTP
+	// transport needs it, since Streak schedules the draft Gmail already has.
+	Raw []byte
+}
+
+// Scheduler dispatches a drafted email to be sent later over some
+// transport (Streak's private API, a self-hosted SMTP relay, ...).
+type Scheduler interface {
+	ScheduleDraft(ctx context.Context, req *ScheduleRequest, sendTime time.Time) error
+}
+
+// StreakScheduler schedules sends via Streak's "send later" feature,
+// operating on the draft already sitting in the user's Gmail account.
+type StreakScheduler struct {
+	Token string
+}
+
+// ScheduleDraft implements Scheduler by calling streak.ScheduleSendLater.
+func (s *StreakScheduler) ScheduleDraft(ctx context.Context, req *ScheduleRequest, sendTime time.Time) error {
+	if s.Token == "" {
+		return fmt.Errorf("scheduling error: no streak token provided")
+	}
+
+	return streak.ScheduleSendLater(&streak.SendLaterConfig{
+		Token:        s.Token,
+		ToAddress:    req.ToAddress,
+		Subject:      req.Subject,
+		ThreadID:     req.Draft.Message.ThreadId,
+		DraftID:      req.Draft.Id,
+		SendDate:     sendTime,
+		IsTracked:    true,
+		EmailAddress: req.EmailAddress,
+	})
+}
@@ -1,24 +1,42 @@
 package main
 
 import (
+	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"log"
 	"os"
+	"strconv"
 	"strings"
 	"text/template"
 	"time"
 
+	"emailer/internal/api"
 	"emailer/internal/argparse"
 	"emailer/internal/config"
 	"emailer/internal/gmail"
+	"emailer/internal/policy"
 	"emailer/internal/scheduler"
-	"emailer/internal/streak"
 
 	"github.com/joho/godotenv"
 )
 
-func authenticateGmail(client *gmail.Client, tokenPath, credsPath string) (*gmail.Credentials, error) {
+func authenticateGmail(client *gmail.Client, tokenPath, credsPath, saKeyPath, impersonateUser string) (*gmail.Credentials, error) {
+	// A service account key takes priority: it's a self-renewing JWT flow
+	// with no token file to read or write, so it bypasses everything below.
+	if saKeyPath != "" {
+		if impersonateUser == "" {
+			return nil, fmt.Errorf("--impersonate (or %s) is required when using --sa-key", config.EnvImpersonateUser)
+		}
+		log.Printf("Authenticating as service account, impersonating %s", impersonateUser)
+		return client.LoginWithServiceAccount(saKeyPath, impersonateUser)
+	}
+
+	if credsPath == "" {
+		credsPath = "credentials.json"
+	}
+
 	// Try to load existing token
 	if _, err := os.Stat(tokenPath); err == nil {
 		tokenData, err := os.ReadFile(tokenPath)
@@ -31,14 +49,10 @@ func authenticateGmail(client *gmail.Client, tokenPath, credsPath string) (*gmai
 			return nil, fmt.Errorf("failed to parse token file: %v", err)
 		}
 
-		return client.LoginWithToken(&token)
+		return client.LoginWithToken(&token, credsPath)
 	}
 
 	// Try logging in with credentials
-	if credsPath == "" {
-		credsPath = "credentials.json"
-	}
-
 	if _, err := os.Stat(credsPath); err != nil {
 		return nil, fmt.Errorf("no credentials JSON file found")
 	}
@@ -75,16 +89,74 @@ func processTemplate(templateStr string, data map[string]string) (string, error)
 	return result.String(), nil
 }
 
-func scheduleSend(timezone, csvPath string, draft *gmail.Draft, streakToken, streakEmailAddress, toAddress, subject string) error {
-	if streakToken == "" {
-		return fmt.Errorf("scheduling error: no streak token provided")
+// loadMessageBody renders the HTML body at htmlPath. If htmlPath ends in
+// .html and a sibling file with the same name but a .txt extension exists,
+// it's rendered too and used as the explicit plain-text alternative instead
+// of the one MessageBuilder would otherwise derive from the HTML.
+func loadMessageBody(htmlPath string, data map[string]string) (html, text string, err error) {
+	htmlContent, err := os.ReadFile(htmlPath)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read message template: %v", err)
 	}
+
+	html, err = processTemplate(string(htmlContent), data)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to process template: %v", err)
+	}
+
+	if strings.HasSuffix(htmlPath, ".html") {
+		txtPath := strings.TrimSuffix(htmlPath, ".html") + ".txt"
+		if txtContent, err := os.ReadFile(txtPath); err == nil {
+			text, err = processTemplate(string(txtContent), data)
+			if err != nil {
+				return "", "", fmt.Errorf("failed to process plain-text template: %v", err)
+			}
+		}
+	}
+
+	return html, text, nil
+}
+
+// buildScheduler resolves the --transport flag to a concrete
+// scheduler.Scheduler, reading whatever backend-specific config (Streak
+// token, SMTP relay settings) that transport needs.
+func buildScheduler(transport string) (scheduler.Scheduler, error) {
+	switch transport {
+	case "", "streak":
+		return &scheduler.StreakScheduler{
+			Token: argparse.GetArgOrEnv("", config.EnvStreakToken, true, ""),
+		}, nil
+	case "smtp":
+		port, err := strconv.Atoi(argparse.GetArgOrEnv("", config.EnvSMTPPort, false, "587"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", config.EnvSMTPPort, err)
+		}
+		return &scheduler.SMTPScheduler{
+			Config: scheduler.SMTPConfig{
+				Host:     argparse.GetArgOrEnv("", config.EnvSMTPHost, true, ""),
+				Port:     port,
+				Username: argparse.GetArgOrEnv("", config.EnvSMTPUser, false, ""),
+				Password: argparse.GetArgOrEnv("", config.EnvSMTPPassword, false, ""),
+				StartTLS: argparse.GetBoolArgOrEnv(false, config.EnvSMTPStartTLS),
+			},
+			QueuePath: argparse.GetArgOrEnv("", config.EnvSMTPQueuePath, false, "smtp_queue.json"),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown transport %q (expected streak or smtp)", transport)
+	}
+}
+
+// scheduleSend computes a send time from the schedule CSV and dispatches
+// the drafted email to sched for delivery at that time. rawMessage is the
+// RFC 5322 message as originally built for the draft; it's only used by
+// transports (like SMTP) that don't operate on the Gmail draft itself.
+func scheduleSend(timezone, csvPath string, draft *gmail.Draft, rawMessage []byte, sched scheduler.Scheduler, streakEmailAddress, toAddress, subject string) (*time.Time, error) {
 	if csvPath == "" {
-		return fmt.Errorf("scheduling error: no schedule csv file provided")
+		return nil, fmt.Errorf("scheduling error: no schedule csv file provided")
 	}
 
 	if _, err := os.Stat(csvPath); err != nil {
-		return fmt.Errorf("scheduling error: no schedule csv file found")
+		return nil, fmt.Errorf("scheduling error: no schedule csv file found")
 	}
 
 	if streakEmailAddress == "" {
@@ -94,12 +166,12 @@ func scheduleSend(timezone, csvPath string, draft *gmail.Draft, streakToken, str
 	// Parse CSV and get scheduled time
 	dayRanges, err := scheduler.ParseTimeRangesCSV(csvPath)
 	if err != nil {
-		return fmt.Errorf("failed to parse CSV: %v", err)
+		return nil, fmt.Errorf("failed to parse CSV: %v", err)
 	}
 
 	sendTime, err := scheduler.GetScheduledSendTime(dayRanges, timezone)
 	if err != nil {
-		return fmt.Errorf("failed to get scheduled time: %v", err)
+		return nil, fmt.Errorf("failed to get scheduled time: %v", err)
 	}
 
 	if sendTime == nil {
@@ -113,18 +185,75 @@ func scheduleSend(timezone, csvPath string, draft *gmail.Draft, streakToken, str
 		sendTime = &now
 	}
 
-	config := &streak.SendLaterConfig{
-		Token:        streakToken,
+	req := &scheduler.ScheduleRequest{
+		Draft:        draft,
 		ToAddress:    toAddress,
 		Subject:      subject,
-		ThreadID:     draft.Message.ThreadId,
-		DraftID:      draft.Id,
-		SendDate:     *sendTime,
-		IsTracked:    true,
 		EmailAddress: streakEmailAddress,
+		Raw:          rawMessage,
+	}
+
+	if err := sched.ScheduleDraft(context.Background(), req, *sendTime); err != nil {
+		return nil, err
+	}
+
+	return sendTime, nil
+}
+
+// runServe authenticates once and runs the tool as a long-lived HTTP API
+// server instead of sending a single email.
+func runServe(args *argparse.Args, gmailClient *gmail.Client) error {
+	tokenPathArg, credsPathArg := argparse.AuthArgs(args)
+	tokenPath := argparse.GetArgOrEnv(tokenPathArg, config.EnvTokenPath, false, "token.json")
+	saKeyPath, impersonateUser := argparse.ServiceAccountArgs(args)
+
+	creds, err := authenticateGmail(gmailClient, tokenPath, credsPathArg, saKeyPath, impersonateUser)
+	if err != nil {
+		return fmt.Errorf("authentication failed: %v", err)
+	}
+
+	if saKeyPath == "" {
+		if err := saveCredentials(creds, tokenPath); err != nil {
+			log.Printf("Warning: Failed to save credentials: %v", err)
+		}
+	}
+
+	_, emailAddressArg, scheduleCsvPathArg, timezoneArg := argparse.SchedulingArgs(args)
+
+	apiToken := argparse.GetArgOrEnv("", config.EnvAPIToken, true, "")
+	port := argparse.GetArgOrEnv(argparse.APIPortArg(args), config.EnvAPIPort, false, "8080")
+	streakToken := argparse.GetArgOrEnv("", config.EnvStreakToken, false, "")
+	streakEmailAddress := argparse.GetArgOrEnv(emailAddressArg, config.EnvStreakEmailAddress, false, "")
+	csvPath := argparse.GetArgOrEnv(scheduleCsvPathArg, config.EnvScheduleCsvPath, false, "")
+	timezone := argparse.GetArgOrEnv(timezoneArg, config.EnvTimezone, false, "UTC")
+
+	if streakEmailAddress == "" {
+		if user, err := gmailClient.GetCurrentUser(); err != nil {
+			log.Printf("Warning: Failed to get current user: %v", err)
+		} else {
+			streakEmailAddress = user.EmailAddress
+		}
 	}
 
-	return streak.ScheduleSendLater(config)
+	allowlistPath, blocklistPath := argparse.PolicyPaths(args)
+	sendPolicy, err := policy.Load(allowlistPath, blocklistPath, argparse.IsDryRun(args))
+	if err != nil {
+		return fmt.Errorf("failed to load allowlist/blocklist: %v", err)
+	}
+
+	cfg := &api.Config{
+		Addr:               ":" + port,
+		AuthToken:          apiToken,
+		StreakToken:        streakToken,
+		StreakEmailAddress: streakEmailAddress,
+		ScheduleCsvPath:    csvPath,
+		Timezone:           timezone,
+		Policy:             sendPolicy,
+		TokenSecret:        argparse.GetArgOrEnv("", config.EnvTokenSecret, false, ""),
+		BlocklistPath:      blocklistPath,
+	}
+
+	return api.Serve(cfg, gmailClient)
 }
 
 func main() {
@@ -133,59 +262,109 @@ func main() {
 		log.Printf("Warning: .env file not found: %v", err)
 	}
 
+	// `emailer campaign recipients.csv` runs a bulk send instead of the
+	// single-recipient flow, so it's dispatched before the recruiter
+	// positional args are parsed.
+	if len(os.Args) > 1 && os.Args[1] == "campaign" {
+		if err := runCampaign(os.Args[2:], gmail.NewClient()); err != nil {
+			log.Fatalf("Campaign error: %v", err)
+		}
+		return
+	}
+
+	// `emailer watch` runs the IMAP reply-detection loop instead of the
+	// single-recipient flow.
+	if len(os.Args) > 1 && os.Args[1] == "watch" {
+		if err := runWatch(os.Args[2:], gmail.NewClient()); err != nil {
+			log.Fatalf("Watch error: %v", err)
+		}
+		return
+	}
+
+	// `emailer smtp-daemon` runs the worker that delivers messages queued by
+	// the SMTP scheduler transport (--transport=smtp). It never touches
+	// Gmail, so it's dispatched before a client is created.
+	if len(os.Args) > 1 && os.Args[1] == "smtp-daemon" {
+		if err := runSMTPDaemon(os.Args[2:]); err != nil {
+			log.Fatalf("SMTP daemon error: %v", err)
+		}
+		return
+	}
+
 	// Parse command line arguments
 	// create the arg variable. it should be empty struct of argparse.Args
 	args := &argparse.Args{}
 	argparse.ParseArgs(args)
 	// args := argparse.ParseArgs()
 
+	// Create Gmail client
+	gmailClient := gmail.NewClient()
+
+	if argparse.IsServeMode(args) {
+		if err := runServe(args, gmailClient); err != nil {
+			log.Fatalf("API server error: %v", err)
+		}
+		return
+	}
+
 	// Validate required arguments
 	if err := argparse.ValidateArgs(args); err != nil {
 		log.Fatalf("Validation error: %v", err)
 	}
 
-	// Create Gmail client
-	gmailClient := gmail.NewClient()
-
-	// Get values from args or env vars
-	subject := argparse.GetArgOrEnv(args.subject, config.EnvEmailSubject, true, "")
-	messageBodyPath := argparse.GetArgOrEnv(args.messageBodyPath, config.EnvMessageBodyPath, true, "")
-	attachmentPathString := argparse.GetArgOrEnv(args.attachmentPath, config.EnvAttachmentPath, false, "")
-	attachmentName := argparse.GetArgOrEnv(args.attachmentName, config.EnvAttachmentName, false, "")
-
-	// Validate attachment parameters
-	if (attachmentPathString != "") != (attachmentName != "") {
-		log.Fatal("attachment_path and attachment_name must both appear if either is provided")
+	allowlistPath, blocklistPath := argparse.PolicyPaths(args)
+	sendPolicy, err := policy.Load(allowlistPath, blocklistPath, argparse.IsDryRun(args))
+	if err != nil {
+		log.Fatalf("Failed to load allowlist/blocklist: %v", err)
+	}
+	recruiterCompany, recruiterName, recruiterEmail := argparse.RecruiterArgs(args)
+	if err := sendPolicy.Check(recruiterEmail); err != nil {
+		log.Fatalf("Recipient rejected by policy: %v", err)
 	}
 
-	// Validate attachment file exists if path is provided
-	if attachmentPathString != "" {
-		if _, err := os.Stat(attachmentPathString); err != nil {
+	// Get values from args or env vars
+	subjectArg, messageBodyPathArg := argparse.EmailContentArgs(args)
+	subject := argparse.GetArgOrEnv(subjectArg, config.EnvEmailSubject, true, "")
+	messageBodyPath := argparse.GetArgOrEnv(messageBodyPathArg, config.EnvMessageBodyPath, true, "")
+	attachmentPaths := argparse.AttachmentPaths(args)
+	attachmentNames := argparse.AttachmentNames(args)
+
+	// Validate attachment files exist
+	for _, path := range attachmentPaths {
+		cleanPath, _ := gmail.SplitAttachmentPath(path)
+		if _, err := os.Stat(cleanPath); err != nil {
 			log.Fatalf("Attachment file not found: %v", err)
 		}
 	}
 
-	shouldSchedule := getBoolArgOrEnv(args.schedule, config.EnvEnableStreakScheduling)
-	tokenPath := getArgOrEnv(args.tokenPath, config.EnvTokenPath, false, "token.json")
+	scheduleArg, emailAddressArg, scheduleCsvPathArg, timezoneArg := argparse.SchedulingArgs(args)
+	shouldSchedule := argparse.GetBoolArgOrEnv(scheduleArg, config.EnvEnableStreakScheduling)
+	tokenPathArg, credsPathArg := argparse.AuthArgs(args)
+	tokenPath := argparse.GetArgOrEnv(tokenPathArg, config.EnvTokenPath, false, "token.json")
+	saKeyPath, impersonateUser := argparse.ServiceAccountArgs(args)
 
 	// Login with token
-	creds, err := authenticateGmail(gmailClient, tokenPath, args.credsPath)
+	creds, err := authenticateGmail(gmailClient, tokenPath, credsPathArg, saKeyPath, impersonateUser)
 	if err != nil {
 		log.Fatalf("Authentication failed: %v", err)
 	}
 
 	// Save updated credentials
-	if err := saveCredentials(creds, tokenPath); err != nil {
-		log.Printf("Warning: Failed to save credentials: %v", err)
+	if saKeyPath == "" {
+		if err := saveCredentials(creds, tokenPath); err != nil {
+			log.Printf("Warning: Failed to save credentials: %v", err)
+		}
 	}
 
 	// Setup email contents
-	var attachment []byte
-	if attachmentPathString != "" {
-		attachment, err = os.ReadFile(attachmentPathString)
+	var attachments []gmail.Attachment
+	for i, path := range attachmentPaths {
+		cleanPath, inline := gmail.SplitAttachmentPath(path)
+		data, err := os.ReadFile(cleanPath)
 		if err != nil {
 			log.Fatalf("Failed to read attachment file: %v", err)
 		}
+		attachments = append(attachments, gmail.NewAttachment(attachmentNames[i], data, inline))
 	}
 
 	// Validate message template file exists
@@ -193,36 +372,45 @@ func main() {
 		log.Fatalf("Message template file not found: %v", err)
 	}
 
-	templateContent, err := os.ReadFile(messageBodyPath)
-	if err != nil {
-		log.Fatalf("Failed to read message template: %v", err)
-	}
-
-	emailContents, err := processTemplate(string(templateContent), map[string]string{
-		"recruiter_name":    args.recruiterName,
-		"recruiter_company": args.recruiterCompany,
+	emailContents, plainTextContents, err := loadMessageBody(messageBodyPath, map[string]string{
+		"recruiter_name":    recruiterName,
+		"recruiter_company": recruiterCompany,
 	})
 	if err != nil {
-		log.Fatalf("Failed to process template: %v", err)
+		log.Fatalf("%v", err)
 	}
 
 	subject, err = processTemplate(subject, map[string]string{
-		"recruiter_company": args.recruiterCompany,
+		"recruiter_company": recruiterCompany,
 	})
 	if err != nil {
 		log.Fatalf("Failed to process subject template: %v", err)
 	}
 
-	emailMessage := gmail.CreateEmailMessage(
-		emailContents,
-		args.recruiterEmail,
-		subject,
-		attachment,
-		attachmentName,
-	)
+	var action *gmail.ViewAction
+	if actionLink, actionName, actionDescription := argparse.ActionArgs(args); actionLink != "" {
+		action = &gmail.ViewAction{Link: actionLink, Name: actionName, Description: actionDescription}
+	}
+
+	emailMessage, err := gmail.BuildMessage(&gmail.EmailMessage{
+		To:          recruiterEmail,
+		Subject:     subject,
+		Body:        emailContents,
+		Text:        plainTextContents,
+		Attachments: attachments,
+		Action:      action,
+	})
+	if err != nil {
+		log.Fatalf("Failed to build email message: %v", err)
+	}
 
 	log.Printf("Recruiter email: %s, Recruiter Name: %s, Recruiter Company: %s",
-		args.recruiterEmail, args.recruiterName, args.recruiterCompany)
+		recruiterEmail, recruiterName, recruiterCompany)
+
+	if sendPolicy.DryRun {
+		fmt.Printf("[dry-run] Would send to %s\nSubject: %s\n%s\n", recruiterEmail, subject, emailContents)
+		return
+	}
 
 	// Save draft
 	draft, err := gmailClient.SaveDraft(emailMessage)
@@ -232,10 +420,9 @@ func main() {
 
 	// Schedule email if requested
 	if shouldSchedule {
-		timezone := getArgOrEnv(args.timezone, config.EnvTimezone, false, "UTC")
-		streakToken := getArgOrEnv("", config.EnvStreakToken, true, "")
-		csvPath := getArgOrEnv(args.scheduleCsvPath, config.EnvScheduleCsvPath, true, "")
-		streakEmailAddress := getArgOrEnv(args.emailAddress, config.EnvStreakEmailAddress, false, "")
+		timezone := argparse.GetArgOrEnv(timezoneArg, config.EnvTimezone, false, "UTC")
+		csvPath := argparse.GetArgOrEnv(scheduleCsvPathArg, config.EnvScheduleCsvPath, true, "")
+		streakEmailAddress := argparse.GetArgOrEnv(emailAddressArg, config.EnvStreakEmailAddress, false, "")
 
 		if streakEmailAddress == "" {
 			user, err := gmailClient.GetCurrentUser()
@@ -246,7 +433,17 @@ func main() {
 			}
 		}
 
-		if err := scheduleSend(timezone, csvPath, draft, streakToken, streakEmailAddress, args.recruiterEmail, subject); err != nil {
+		sched, err := buildScheduler(argparse.Transport(args))
+		if err != nil {
+			log.Fatalf("Failed to configure scheduler: %v", err)
+		}
+
+		rawMessage, err := base64.URLEncoding.DecodeString(emailMessage.Raw)
+		if err != nil {
+			log.Printf("Warning: failed to decode message for scheduling: %v", err)
+		}
+
+		if _, err := scheduleSend(timezone, csvPath, draft, rawMessage, sched, streakEmailAddress, recruiterEmail, subject); err != nil {
 			log.Printf("Warning: Failed to schedule email: %v", err)
 		}
 	}
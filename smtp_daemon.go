@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"emailer/internal/argparse"
+	"emailer/internal/config"
+	"emailer/internal/scheduler"
+
+	"github.com/spf13/pflag"
+)
+
+// runSMTPDaemon runs the long-lived worker that delivers messages enqueued
+// by the SMTP scheduler (see internal/scheduler/smtp.go) once they're due.
+// It doesn't touch Gmail at all, so unlike the other subcommands it takes
+// no gmail.Client.
+func runSMTPDaemon(daemonArgs []string) error {
+	fs := pflag.NewFlagSet("smtp-daemon", pflag.ExitOnError)
+	queuePath := fs.StringP("queue", "q", "", fmt.Sprintf("Path to the SMTP scheduler's on-disk queue file. Overrides the %s environment variable", config.EnvSMTPQueuePath))
+	pollInterval := fs.DurationP("poll-interval", "", time.Minute, "How often to check the queue for due messages")
+	host := fs.StringP("smtp_host", "", "", fmt.Sprintf("SMTP relay host. Overrides the %s environment variable", config.EnvSMTPHost))
+	port := fs.IntP("smtp_port", "", 0, fmt.Sprintf("SMTP relay port. Overrides the %s environment variable", config.EnvSMTPPort))
+	username := fs.StringP("smtp_user", "", "", fmt.Sprintf("SMTP username. Overrides the %s environment variable", config.EnvSMTPUser))
+	password := fs.StringP("smtp_password", "", "", fmt.Sprintf("SMTP password. Overrides the %s environment variable", config.EnvSMTPPassword))
+	startTLS := fs.BoolP("smtp_starttls", "", false, fmt.Sprintf("Use STARTTLS when connecting to the relay. Overrides the %s environment variable", config.EnvSMTPStartTLS))
+
+	if err := fs.Parse(daemonArgs); err != nil {
+		return err
+	}
+
+	portArg := ""
+	if *port != 0 {
+		portArg = strconv.Itoa(*port)
+	}
+	resolvedPort, err := strconv.Atoi(argparse.GetArgOrEnv(portArg, config.EnvSMTPPort, false, "587"))
+	if err != nil {
+		return fmt.Errorf("invalid %s: %v", config.EnvSMTPPort, err)
+	}
+
+	cfg := scheduler.SMTPConfig{
+		Host:     argparse.GetArgOrEnv(*host, config.EnvSMTPHost, true, ""),
+		Port:     resolvedPort,
+		Username: argparse.GetArgOrEnv(*username, config.EnvSMTPUser, false, ""),
+		Password: argparse.GetArgOrEnv(*password, config.EnvSMTPPassword, false, ""),
+		StartTLS: *startTLS || argparse.GetArgOrEnv("", config.EnvSMTPStartTLS, false, "") == "true",
+	}
+	resolvedQueuePath := argparse.GetArgOrEnv(*queuePath, config.EnvSMTPQueuePath, false, "smtp_queue.json")
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	log.Printf("SMTP daemon watching %s (relay: %s)", resolvedQueuePath, cfg.Host)
+	return scheduler.RunDaemon(ctx, resolvedQueuePath, cfg, *pollInterval)
+}
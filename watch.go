@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"emailer/internal/argparse"
+	"emailer/internal/config"
+	"emailer/internal/gmail"
+	"emailer/internal/imap"
+
+	"github.com/spf13/pflag"
+)
+
+// runWatch authenticates once, then watches INBOX over IMAP for replies to
+// previously sent messages so scheduled follow-ups to those recipients can
+// be cancelled automatically.
+func runWatch(watchArgs []string, gmailClient *gmail.Client) error {
+	fs := pflag.NewFlagSet("watch", pflag.ExitOnError)
+	storePath := fs.StringP("store", "", "followups.json", "Path to the JSON store tracking sent messages and their scheduled follow-up drafts")
+	pollInterval := fs.DurationP("poll-interval", "", time.Minute, "How often to re-check INBOX for replies")
+	tokenPath := fs.StringP("token_path", "t", "", fmt.Sprintf("The path to the token.json file. The default value is token.json. Overrides the %s environment variable", config.EnvTokenPath))
+	credsPath := fs.StringP("creds_path", "c", "", fmt.Sprintf("The path to the credentials.json file. Overrides the %s environment variable", config.EnvCredsPath))
+
+	if err := fs.Parse(watchArgs); err != nil {
+		return err
+	}
+
+	saKeyPath := argparse.GetArgOrEnv("", config.EnvSAKeyPath, false, "")
+	impersonateUser := argparse.GetArgOrEnv("", config.EnvImpersonateUser, false, "")
+
+	resolvedTokenPath := argparse.GetArgOrEnv(*tokenPath, config.EnvTokenPath, false, "token.json")
+	resolvedCredsPath := argparse.GetArgOrEnv(*credsPath, config.EnvCredsPath, false, "")
+
+	creds, err := authenticateGmail(gmailClient, resolvedTokenPath, resolvedCredsPath, saKeyPath, impersonateUser)
+	if err != nil {
+		return fmt.Errorf("authentication failed: %v", err)
+	}
+	if saKeyPath == "" {
+		if err := saveCredentials(creds, resolvedTokenPath); err != nil {
+			log.Printf("Warning: Failed to save credentials: %v", err)
+		}
+	}
+
+	user, err := gmailClient.GetCurrentUser()
+	if err != nil {
+		return fmt.Errorf("failed to get current user: %v", err)
+	}
+
+	imapClient, err := imap.Dial(user.EmailAddress, creds.Token.AccessToken)
+	if err != nil {
+		return fmt.Errorf("failed to connect to IMAP: %v", err)
+	}
+	defer imapClient.Close()
+
+	store, err := imap.OpenStore(*storePath)
+	if err != nil {
+		return fmt.Errorf("failed to open follow-up store: %v", err)
+	}
+
+	watcher := imap.NewWatcher(imapClient, gmailClient, store)
+	watcher.PollInterval = *pollInterval
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	log.Printf("Watching %s's INBOX for replies (store: %s)", user.EmailAddress, *storePath)
+	return watcher.Run(ctx)
+}